@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type AppsCmd struct {
+	Env    string `kong:"optional,short='e',help='Only list apps in this environment (Production or Stage2).'"`
+	Filter string `kong:"optional,short='f',help='Only list apps whose name or machine contains this text.'"`
+}
+
+type KeaAppDetail struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Version string `json:"version"`
+	Machine struct {
+		ID       int    `json:"id"`
+		Address  string `json:"address"`
+		Hostname string `json:"hostname"`
+	} `json:"machine"`
+	AccessPoints []struct {
+		Type              string `json:"type"`
+		Address           string `json:"address"`
+		Port              int    `json:"port"`
+		UseSecureProtocol bool   `json:"useSecureProtocol"`
+	} `json:"accessPoints"`
+	Details struct {
+		Daemons []KeaDaemon `json:"daemons"`
+	} `json:"details"`
+
+	// envName is the environment the app was looked up in, for the
+	// control agent credentials.
+	envName string
+}
+
+type KeaDaemon struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Active     bool   `json:"active"`
+	Version    string `json:"version"`
+	Uptime     int    `json:"uptime"`
+	LogTargets []struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		Output   string `json:"output"`
+		Severity string `json:"severity"`
+	} `json:"logTargets"`
+}
+
+// ControlAddress returns the Kea control agent endpoint of the app.
+func (a *KeaAppDetail) ControlAddress() string {
+	for _, ap := range a.AccessPoints {
+		if ap.Type != "control" {
+			continue
+		}
+		scheme := "http"
+		if ap.UseSecureProtocol {
+			scheme = "https"
+		}
+		return fmt.Sprintf("%s://%s:%d", scheme, ap.Address, ap.Port)
+	}
+	return ""
+}
+
+// getAppDetails fetches every Kea app in an environment with its daemons.
+// Unlike getApps this always goes to Stork, as daemon state changes.
+func getAppDetails(envURL *url.URL, jar *cookiejar.Jar) ([]KeaAppDetail, error) {
+	query := url.Values{}
+	query.Set("app", "kea")
+	apps, err := getAllPages[KeaAppDetail]("/api/apps", query, envURL, jar)
+	if err != nil {
+		return nil, fmt.Errorf("error listing apps: %w", err)
+	}
+	return apps, nil
+}
+
+// selectEnvironments returns the environments to query: all of them, or only
+// the named one.
+func selectEnvironments(name string) (map[string]string, error) {
+	if name == "" {
+		return environments, nil
+	}
+	for _, envName := range environmentNames(environments) {
+		if strings.EqualFold(envName, name) {
+			return map[string]string{envName: environments[envName]}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown environment %q", name)
+}
+
+// environmentNames returns the names of envs in order, so output doesn't
+// change from run to run.
+func environmentNames(envs map[string]string) []string {
+	var names []string
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *AppsCmd) Run() error {
+	selected, err := selectEnvironments(a.Env)
+	if err != nil {
+		return err
+	}
+
+	for _, envName := range environmentNames(selected) {
+		envURL, err := url.Parse(selected[envName])
+		if err != nil {
+			fmt.Printf("Error parsing environment URLs: %v", err.Error())
+			return err
+		}
+
+		jar, err := storkAuth(envURL)
+		if err != nil {
+			fmt.Printf("%s: %s\n", envName, err.Error())
+			continue
+		}
+
+		apps, err := getAppDetails(envURL, jar)
+		if err != nil {
+			fmt.Printf("%s: %s\n", envName, err.Error())
+			continue
+		}
+		sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetAutoWrapText(false)
+		table.SetBorder(false)
+		table.SetRowLine(true)
+		table.SetHeader([]string{"ID", "Kea Instance", "Machine", "Control Agent", "Daemons", "Log Targets"})
+		count := 0
+		for _, app := range apps {
+			filter := strings.ToLower(a.Filter)
+			if filter != "" &&
+				!strings.Contains(strings.ToLower(app.Name), filter) &&
+				!strings.Contains(strings.ToLower(app.Machine.Address), filter) &&
+				!strings.Contains(strings.ToLower(app.Machine.Hostname), filter) {
+				continue
+			}
+
+			var daemons, logTargets []string
+			for _, daemon := range app.Details.Daemons {
+				state := "active"
+				if !daemon.Active {
+					state = "inactive"
+				}
+				daemons = append(daemons, fmt.Sprintf("%s %s (%s)", daemon.Name, daemon.Version, state))
+
+				var targets []string
+				for _, target := range daemon.LogTargets {
+					targets = append(targets, fmt.Sprintf("%s -> %s [%s]", target.Name, target.Output, target.Severity))
+				}
+				logTargets = append(logTargets, strings.Join(targets, ", "))
+			}
+
+			machine := app.Machine.Hostname
+			if machine == "" {
+				machine = app.Machine.Address
+			}
+
+			table.Append([]string{
+				strconv.Itoa(app.ID),
+				app.Name,
+				strings.Replace(machine, ".internal.digitalocean.com", "", 1),
+				app.ControlAddress(),
+				strings.Join(daemons, "\n"),
+				strings.Join(logTargets, "\n"),
+			})
+			count++
+		}
+		fmt.Printf("\n%s: (%d Kea apps)\n", envName, count)
+		table.Render()
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectEnvironments(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+		err  string
+	}{
+		{name: "", want: environmentNames(environments)},
+		{name: "production", want: []string{"Production"}},
+		{name: "STAGE2", want: []string{"Stage2"}},
+		{name: "lab", err: `unknown environment "lab"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected, err := selectEnvironments(test.name)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := environmentNames(selected); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEnvironmentNames(t *testing.T) {
+	envs := map[string]string{"Staging": "s", "Production": "p", "Lab": "l", "Dev": "d"}
+	want := []string{"Dev", "Lab", "Production", "Staging"}
+	for i := 0; i < 10; i++ {
+		if got := environmentNames(envs); !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
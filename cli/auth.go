@@ -3,12 +3,10 @@ package cli
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"net/http"
 	"net/http/cookiejar"
 	"net/url"
-	"os"
+	"sort"
 )
 
 var (
@@ -18,36 +16,38 @@ var (
 	}
 )
 
-func storkAuth(environment *url.URL) (*cookiejar.Jar, error) {
-	storkEmail, ok := os.LookupEnv("STORK_USER")
-	if !ok {
-		fmt.Print("Unset environment variables! Please define:\n" +
-			"'STORK_USER' and 'STORK_PASS' in your shell\n" +
-			"with values from Vault: stork-dhcp/tools\n")
-		os.Exit(127)
+// environmentName returns the name of the environment served from
+// environment, or its host if it isn't one of ours.
+func environmentName(environment *url.URL) string {
+	for name, envURL := range environments {
+		if u, err := url.Parse(envURL); err == nil && u.Host == environment.Host {
+			return name
+		}
 	}
-	storkPassword, ok := os.LookupEnv("STORK_PASS")
-	if !ok {
-		fmt.Print("Unset environment variables! Please define:\n" +
-			"'STORK_USER' and 'STORK_PASS' in your shell\n" +
-			"with values from Vault: stork-dhcp/tools\n")
-		os.Exit(127)
+	return environment.Host
+}
+
+func storkAuth(environment *url.URL) (*cookiejar.Jar, error) {
+	creds, err := lookupCredentials(environmentName(environment), environment)
+	if err != nil {
+		return nil, err
 	}
 
 	postBody, _ := json.Marshal(map[string]string{
-		"useremail":    storkEmail,
-		"userpassword": storkPassword,
+		"useremail":    creds.User,
+		"userpassword": creds.Password,
 	})
 
-	resp, err := http.Post(environment.String()+"/api/sessions", "application/json", bytes.NewBuffer(postBody))
+	resp, err := storkClient(nil).Post(environment.String()+"/api/sessions", "application/json", bytes.NewBuffer(postBody))
 	if err != nil {
-		fmt.Printf("\nan error occured authenticating with: %s", err.Error())
+		return nil, fmt.Errorf("an error occured authenticating with %s: %w", environment.Host, err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode == 200 {
 		jar, err := cookiejar.New(nil)
 		if err != nil {
-			return nil, errors.New("could not create auth cookie storage")
+			return nil, fmt.Errorf("could not create auth cookie storage: %w", err)
 		}
 		jar.SetCookies(environment, resp.Cookies())
 		return jar, nil
@@ -56,3 +56,32 @@ func storkAuth(environment *url.URL) (*cookiejar.Jar, error) {
 	}
 
 }
+
+// stork is a logged-in Stork environment.
+type stork struct {
+	name string
+	url  *url.URL
+	jar  *cookiejar.Jar
+}
+
+// storkLogin logs in to every environment, in name order. Environments that
+// fail are reported and skipped.
+func storkLogin() ([]stork, error) {
+	var envs []stork
+	for envName, environment := range environments {
+		envURL, err := url.Parse(environment)
+		if err != nil {
+			fmt.Printf("Error parsing environment URLs: %v", err.Error())
+			return nil, err
+		}
+
+		jar, err := storkAuth(envURL)
+		if err != nil {
+			fmt.Printf("%s: %s\n", envName, err.Error())
+			continue
+		}
+		envs = append(envs, stork{name: envName, url: envURL, jar: jar})
+	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].name < envs[j].name })
+	return envs, nil
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cachePath returns the path of a named JSON file in the cache directory.
+func cachePath(name string) (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// readCache decodes a cache file into v and returns when it was written.
+func readCache(name string, v interface{}) (time.Time, error) {
+	path, err := cachePath(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), json.Unmarshal(data, v)
+}
+
+// writeCache atomically replaces a cache file with v encoded as JSON.
+func writeCache(name string, v interface{}) error {
+	path, err := cachePath(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,436 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+)
+
+// completionTimeout bounds how long a cache refresh may hold up the shell.
+const completionTimeout = 3 * time.Second
+
+// CompletionCmd prints a shell completion script. Arguments and flags tagged
+// with completion='instances' complete Kea instance names, and those tagged
+// completion='regions' also complete region prefixes, through the hidden
+// CompleteCmd.
+type CompletionCmd struct {
+	Shell string `kong:"arg='',enum='bash,zsh,fish',help='Shell to generate completions for: bash, zsh or fish.'"`
+}
+
+// CompleteCmd prints dynamic completion candidates, one per line.
+type CompleteCmd struct {
+	Kind string `kong:"arg='',enum='instances,regions',help='Kind of candidates to print.'"`
+}
+
+func (c *CompletionCmd) Run(ctx *kong.Context) error {
+	app := ctx.Model
+	switch c.Shell {
+	case "bash":
+		writeBashCompletion(os.Stdout, app)
+	case "zsh":
+		writeZshCompletion(os.Stdout, app)
+	case "fish":
+		writeFishCompletion(os.Stdout, app)
+	}
+	return nil
+}
+
+func (c *CompleteCmd) Run() error {
+	switch c.Kind {
+	case "instances":
+		for _, name := range instanceNames() {
+			fmt.Println(name)
+		}
+	case "regions":
+		names := instanceNames()
+		for _, name := range append(regionNames(names), names...) {
+			fmt.Println(name)
+		}
+	}
+	return nil
+}
+
+// instanceNames returns the Kea app names across all environments from the
+// metadata cache, refreshing stale environments within completionTimeout.
+func instanceNames() []string {
+	var stale []string
+	for _, envName := range environmentNames(environments) {
+		a := KeaApp{}
+		written, err := readCache(appsCacheName(envName), &a)
+		if err != nil || time.Since(written) >= metadataTTL {
+			stale = append(stale, envName)
+		}
+	}
+
+	if len(stale) > 0 {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			// Never prompt for credentials from inside the shell's completion.
+			interactive = false
+			for _, envName := range stale {
+				envURL, err := url.Parse(environments[envName])
+				if err != nil {
+					continue
+				}
+				jar, err := storkAuth(envURL)
+				if err != nil {
+					continue
+				}
+				_, _ = getApps(envURL, jar)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(completionTimeout):
+		}
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, envName := range environmentNames(environments) {
+		a := KeaApp{}
+		if _, err := readCache(appsCacheName(envName), &a); err != nil {
+			continue
+		}
+		for _, item := range a.Items {
+			if !seen[item.AppName] {
+				seen[item.AppName] = true
+				names = append(names, item.AppName)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// regionNames returns the region prefixes of instance names: the name without
+// its trailing number, e.g. NYC for NYC3 and S2R for S2R8.
+func regionNames(instances []string) []string {
+	seen := map[string]bool{}
+	var regions []string
+	for _, name := range instances {
+		region := strings.TrimRight(name, "0123456789")
+		if region != "" && region != name && !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// completionCommand is the part of the kong model the completion scripts
+// need, for the top level (empty Path) or one command or subcommand.
+type completionCommand struct {
+	Path        []string
+	Help        string
+	Flags       []*kong.Flag
+	Subcommands []*kong.Node
+	// Dynamic is the completion kind of the positional arguments, e.g.
+	// instances, completed through CompleteCmd.
+	Dynamic string
+	Values  []string
+}
+
+// Name returns the command as typed, e.g. "config diff".
+func (c completionCommand) Name() string {
+	return strings.Join(c.Path, " ")
+}
+
+// completionCommands flattens the command tree, parents first. Commands carry
+// the flags of their parent commands; global flags are kept apart.
+func completionCommands(app *kong.Application) []completionCommand {
+	var commands []completionCommand
+	var walk func(node *kong.Node, path []string, flags []*kong.Flag)
+	walk = func(node *kong.Node, path []string, flags []*kong.Flag) {
+		cmd := completionCommand{Path: path, Help: node.Help, Flags: flags}
+		for _, child := range node.Children {
+			if !child.Hidden && child.Type == kong.CommandNode {
+				cmd.Subcommands = append(cmd.Subcommands, child)
+			}
+		}
+		for _, arg := range node.Positional {
+			if kind := arg.Tag.Get("completion"); kind != "" {
+				cmd.Dynamic = kind
+			}
+			if arg.Enum != "" {
+				cmd.Values = strings.Split(arg.Enum, ",")
+			}
+		}
+		commands = append(commands, cmd)
+
+		for _, child := range cmd.Subcommands {
+			childFlags := append([]*kong.Flag{}, flags...)
+			for _, flag := range child.Flags {
+				if !flag.Hidden {
+					childFlags = append(childFlags, flag)
+				}
+			}
+			walk(child, append(append([]string{}, path...), child.Name), childFlags)
+		}
+	}
+	walk(app.Node, nil, nil)
+	return commands
+}
+
+func globalFlags(app *kong.Application) []*kong.Flag {
+	var flags []*kong.Flag
+	if app.HelpFlag != nil {
+		flags = append(flags, app.HelpFlag)
+	}
+	for _, flag := range app.Flags {
+		if !flag.Hidden && flag != app.HelpFlag {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+// flagCandidates returns how a flag's value is completed: a CompleteCmd kind
+// from its completion tag, or the values of its enum.
+func flagCandidates(flag *kong.Flag) (string, []string) {
+	if flag.IsBool() {
+		return "", nil
+	}
+	if kind := flag.Tag.Get("completion"); kind != "" {
+		return kind, nil
+	}
+	if flag.Enum != "" {
+		return "", strings.Split(flag.Enum, ",")
+	}
+	return "", nil
+}
+
+func flagWords(flagSets ...[]*kong.Flag) string {
+	var words []string
+	for _, flags := range flagSets {
+		for _, flag := range flags {
+			words = append(words, "--"+flag.Name)
+			if flag.Short != 0 {
+				words = append(words, "-"+string(flag.Short))
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// flagPatterns returns the case patterns matching a flag as the previous
+// word, for the given command (nil for any command).
+func flagPatterns(flag *kong.Flag, cmd *completionCommand) string {
+	pattern := func(word string) string {
+		if cmd == nil {
+			return `*" ` + word + `"`
+		}
+		return `"` + cmd.Name() + " " + word + `"`
+	}
+	patterns := []string{pattern("--" + flag.Name)}
+	if flag.Short != 0 {
+		patterns = append(patterns, pattern("-"+string(flag.Short)))
+	}
+	return strings.Join(patterns, "|")
+}
+
+// commandPatterns returns the case patterns matching every command path.
+func commandPatterns(commands []completionCommand) string {
+	var patterns []string
+	for _, cmd := range commands {
+		if len(cmd.Path) > 0 {
+			patterns = append(patterns, `"`+cmd.Name()+`"`)
+		}
+	}
+	return strings.Join(patterns, "|")
+}
+
+// flagValueCases calls emit with the case pattern, dynamic kind and values of
+// every flag whose value can be completed, command flags before global ones.
+func flagValueCases(commands []completionCommand, global []*kong.Flag, emit func(pattern string, kind string, values []string)) {
+	for i := range commands {
+		for _, flag := range commands[i].Flags {
+			if kind, values := flagCandidates(flag); kind != "" || len(values) > 0 {
+				emit(flagPatterns(flag, &commands[i]), kind, values)
+			}
+		}
+	}
+	for _, flag := range global {
+		if kind, values := flagCandidates(flag); kind != "" || len(values) > 0 {
+			emit(flagPatterns(flag, nil), kind, values)
+		}
+	}
+}
+
+func writeBashCompletion(w io.Writer, app *kong.Application) {
+	commands := completionCommands(app)
+	global := globalFlags(app)
+
+	fmt.Fprintf(w, "# bash completion for %s\n", app.Name)
+	fmt.Fprintf(w, "_%s() {\n", app.Name)
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"`)
+	fmt.Fprintln(w, `    local cmd="" words="" flags="" dynamic="" i`)
+	fmt.Fprintln(w, `    for ((i = 1; i < COMP_CWORD; i++)); do`)
+	fmt.Fprintln(w, `        case "${cmd:+$cmd }${COMP_WORDS[i]}" in`)
+	fmt.Fprintf(w, "        %s) cmd=\"${cmd:+$cmd }${COMP_WORDS[i]}\" ;;\n", commandPatterns(commands))
+	fmt.Fprintln(w, `        esac`)
+	fmt.Fprintln(w, `    done`)
+	fmt.Fprintln(w, `    case "$cmd $prev" in`)
+	flagValueCases(commands, global, func(pattern string, kind string, values []string) {
+		candidates := strings.Join(values, " ")
+		if kind != "" {
+			candidates = fmt.Sprintf("$(%s __complete %s 2>/dev/null)", app.Name, kind)
+		}
+		fmt.Fprintf(w, "    %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", pattern, candidates)
+	})
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w, `    case "$cmd" in`)
+	for _, cmd := range commands {
+		words := cmd.Values
+		for _, sub := range cmd.Subcommands {
+			words = append(words, sub.Name)
+		}
+		fmt.Fprintf(w, "    \"%s\") words=\"%s\"; flags=\"%s\"; dynamic=\"%s\" ;;\n",
+			cmd.Name(), strings.Join(words, " "), flagWords(cmd.Flags, global), cmd.Dynamic)
+	}
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w, `    if [[ "$cur" == -* ]]; then`)
+	fmt.Fprintln(w, `        COMPREPLY=($(compgen -W "$flags" -- "$cur"))`)
+	fmt.Fprintln(w, `        return`)
+	fmt.Fprintln(w, `    fi`)
+	fmt.Fprintf(w, "    [[ -n \"$dynamic\" ]] && words=\"$words $(%s __complete \"$dynamic\" 2>/dev/null)\"\n", app.Name)
+	fmt.Fprintln(w, `    COMPREPLY=($(compgen -W "$words" -- "$cur"))`)
+	fmt.Fprintln(w, `}`)
+	fmt.Fprintf(w, "complete -F _%s %s\n", app.Name, app.Name)
+}
+
+func writeZshCompletion(w io.Writer, app *kong.Application) {
+	commands := completionCommands(app)
+	global := globalFlags(app)
+
+	fmt.Fprintf(w, "#compdef %s\n\n", app.Name)
+	fmt.Fprintf(w, "_%s() {\n", app.Name)
+	fmt.Fprintln(w, `    local cur=$words[CURRENT] prev=$words[CURRENT-1] cmd="" word dynamic=""`)
+	fmt.Fprintln(w, `    local -a subcommands values opts`)
+	fmt.Fprintln(w, `    for word in ${words[2,CURRENT-1]}; do`)
+	fmt.Fprintln(w, `        case "${cmd:+$cmd }$word" in`)
+	fmt.Fprintf(w, "        %s) cmd=\"${cmd:+$cmd }$word\" ;;\n", commandPatterns(commands))
+	fmt.Fprintln(w, `        esac`)
+	fmt.Fprintln(w, `    done`)
+	fmt.Fprintln(w, `    case "$cmd $prev" in`)
+	flagValueCases(commands, global, func(pattern string, kind string, values []string) {
+		if kind != "" {
+			fmt.Fprintf(w, "    %s) compadd -- ${(f)\"$(%s __complete %s 2>/dev/null)\"}; return ;;\n", pattern, app.Name, kind)
+		} else {
+			fmt.Fprintf(w, "    %s) compadd -- %s; return ;;\n", pattern, strings.Join(values, " "))
+		}
+	})
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w, `    case "$cmd" in`)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "    \"%s\")\n", cmd.Name())
+		if len(cmd.Subcommands) > 0 {
+			var subs []string
+			for _, sub := range cmd.Subcommands {
+				subs = append(subs, fmt.Sprintf("'%s:%s'", sub.Name, zshQuote(sub.Help)))
+			}
+			fmt.Fprintf(w, "        subcommands=(%s)\n", strings.Join(subs, " "))
+		}
+		if len(cmd.Values) > 0 {
+			fmt.Fprintf(w, "        values=(%s)\n", strings.Join(cmd.Values, " "))
+		}
+		if cmd.Dynamic != "" {
+			fmt.Fprintf(w, "        dynamic=%s\n", cmd.Dynamic)
+		}
+		fmt.Fprintf(w, "        opts=(%s) ;;\n", flagWords(cmd.Flags, global))
+	}
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w, `    if [[ $cur == -* ]]; then`)
+	fmt.Fprintln(w, `        compadd -- $opts`)
+	fmt.Fprintln(w, `        return`)
+	fmt.Fprintln(w, `    fi`)
+	fmt.Fprintln(w, `    (( ${#subcommands} )) && _describe 'command' subcommands`)
+	fmt.Fprintln(w, `    (( ${#values} )) && compadd -- $values`)
+	fmt.Fprintf(w, "    [[ -n $dynamic ]] && compadd -- ${(f)\"$(%s __complete $dynamic 2>/dev/null)\"}\n", app.Name)
+	fmt.Fprintln(w, `    return 0`)
+	fmt.Fprintln(w, `}`)
+	fmt.Fprintf(w, "\ncompdef _%s %s\n", app.Name, app.Name)
+}
+
+func writeFishCompletion(w io.Writer, app *kong.Application) {
+	commands := completionCommands(app)
+
+	fmt.Fprintf(w, "# fish completion for %s\n", app.Name)
+	fmt.Fprintf(w, "complete -c %s -f\n\n", app.Name)
+
+	// __<app>_command prints the (sub)command typed so far, e.g. "config diff".
+	var paths []string
+	for _, cmd := range commands {
+		if len(cmd.Path) > 0 {
+			paths = append(paths, "'"+cmd.Name()+"'")
+		}
+	}
+	fmt.Fprintf(w, "function __%s_command\n", app.Name)
+	fmt.Fprintln(w, `    set -l cmd ""`)
+	fmt.Fprintln(w, `    for word in (commandline -opc)[2..-1]`)
+	fmt.Fprintln(w, `        set -l next (string trim -- "$cmd $word")`)
+	fmt.Fprintln(w, `        switch $next`)
+	fmt.Fprintf(w, "            case %s\n", strings.Join(paths, " "))
+	fmt.Fprintln(w, `                set cmd $next`)
+	fmt.Fprintln(w, `        end`)
+	fmt.Fprintln(w, `    end`)
+	fmt.Fprintln(w, `    echo $cmd`)
+	fmt.Fprintln(w, `end`)
+	fmt.Fprintf(w, "function __%s_command_is\n", app.Name)
+	fmt.Fprintf(w, "    set -l cmd (__%s_command)\n", app.Name)
+	fmt.Fprintln(w, `    test "$cmd" = "$argv[1]"`)
+	fmt.Fprintln(w, `end`)
+	fmt.Fprintln(w)
+
+	for _, flag := range globalFlags(app) {
+		fmt.Fprintf(w, "complete -c %s%s\n", app.Name, fishFlag(app.Name, flag))
+	}
+	for _, cmd := range commands {
+		condition := fmt.Sprintf(" -n '__%s_command_is \"%s\"'", app.Name, cmd.Name())
+		for _, sub := range cmd.Subcommands {
+			fmt.Fprintf(w, "complete -c %s%s -a %s -d '%s'\n", app.Name, condition, sub.Name, fishQuote(sub.Help))
+		}
+		for _, flag := range cmd.Flags {
+			fmt.Fprintf(w, "complete -c %s%s%s\n", app.Name, condition, fishFlag(app.Name, flag))
+		}
+		if cmd.Dynamic != "" {
+			fmt.Fprintf(w, "complete -c %s%s -a '(%s __complete %s 2>/dev/null)'\n", app.Name, condition, app.Name, cmd.Dynamic)
+		}
+		if len(cmd.Values) > 0 {
+			fmt.Fprintf(w, "complete -c %s%s -a '%s'\n", app.Name, condition, strings.Join(cmd.Values, " "))
+		}
+	}
+}
+
+func fishFlag(appName string, flag *kong.Flag) string {
+	out := " -l " + flag.Name
+	if flag.Short != 0 {
+		out += " -s " + string(flag.Short)
+	}
+	kind, values := flagCandidates(flag)
+	switch {
+	case kind != "":
+		out += fmt.Sprintf(" -x -a '(%s __complete %s 2>/dev/null)'", appName, kind)
+	case len(values) > 0:
+		out += " -x -a '" + strings.Join(values, " ") + "'"
+	case !flag.IsBool():
+		out += " -r"
+	}
+	return out + " -d '" + fishQuote(flag.Help) + "'"
+}
+
+func zshQuote(s string) string {
+	return strings.NewReplacer("'", `'\''`, ":", `\:`).Replace(s)
+}
+
+func fishQuote(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s)
+}
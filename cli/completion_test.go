@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+type testCompletionCLI struct {
+	Channel string `kong:"optional,enum='production,staging',default='production'"`
+	Logs    struct {
+		Instance string `kong:"arg='',completion='instances'"`
+		Format   string `kong:"optional,short='f',enum='text,json',default='text'"`
+	} `kong:"cmd=''"`
+	Config struct {
+		Diff struct {
+			Daemon string `kong:"optional,short='d',enum='dhcp4,dhcp6',default='dhcp4'"`
+		} `kong:"cmd=''"`
+		Drift struct {
+			Instance string `kong:"arg='',optional,completion='regions'"`
+		} `kong:"cmd=''"`
+	} `kong:"cmd=''"`
+	Lease struct {
+		Rm struct {
+			Instance string `kong:"optional,short='i',completion='instances'"`
+			Verbose  bool   `kong:"optional"`
+		} `kong:"cmd=''"`
+	} `kong:"cmd=''"`
+}
+
+func testCompletionApp(t *testing.T) *kong.Application {
+	parser, err := kong.New(&testCompletionCLI{}, kong.Name("dhcli"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parser.Model
+}
+
+func TestCompletionCommands(t *testing.T) {
+	commands := map[string]completionCommand{}
+	for _, cmd := range completionCommands(testCompletionApp(t)) {
+		commands[cmd.Name()] = cmd
+	}
+
+	tests := []struct {
+		name        string
+		subcommands []string
+		flags       []string
+		dynamic     string
+	}{
+		{name: "", subcommands: []string{"logs", "config", "lease"}},
+		{name: "logs", flags: []string{"format"}, dynamic: "instances"},
+		{name: "config", subcommands: []string{"diff", "drift"}},
+		{name: "config diff", flags: []string{"daemon"}},
+		{name: "config drift", dynamic: "regions"},
+		{name: "lease rm", flags: []string{"instance", "verbose"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, ok := commands[test.name]
+			if !ok {
+				t.Fatalf("command %q missing", test.name)
+			}
+			var subcommands, flags []string
+			for _, sub := range cmd.Subcommands {
+				subcommands = append(subcommands, sub.Name)
+			}
+			for _, flag := range cmd.Flags {
+				flags = append(flags, flag.Name)
+			}
+			if !reflect.DeepEqual(subcommands, test.subcommands) {
+				t.Errorf("got subcommands %v, want %v", subcommands, test.subcommands)
+			}
+			if !reflect.DeepEqual(flags, test.flags) {
+				t.Errorf("got flags %v, want %v", flags, test.flags)
+			}
+			if cmd.Dynamic != test.dynamic {
+				t.Errorf("got dynamic %q, want %q", cmd.Dynamic, test.dynamic)
+			}
+		})
+	}
+}
+
+func TestFlagCandidates(t *testing.T) {
+	app := testCompletionApp(t)
+	flags := map[string]*kong.Flag{}
+	for _, cmd := range completionCommands(app) {
+		for _, flag := range cmd.Flags {
+			flags[cmd.Name()+" --"+flag.Name] = flag
+		}
+	}
+	for _, flag := range globalFlags(app) {
+		flags["--"+flag.Name] = flag
+	}
+
+	tests := []struct {
+		flag   string
+		kind   string
+		values []string
+	}{
+		{flag: "logs --format", values: []string{"text", "json"}},
+		{flag: "config diff --daemon", values: []string{"dhcp4", "dhcp6"}},
+		{flag: "lease rm --instance", kind: "instances"},
+		{flag: "lease rm --verbose"},
+		{flag: "--channel", values: []string{"production", "staging"}},
+		{flag: "--help"},
+	}
+	for _, test := range tests {
+		t.Run(test.flag, func(t *testing.T) {
+			flag, ok := flags[test.flag]
+			if !ok {
+				t.Fatalf("flag %q missing", test.flag)
+			}
+			kind, values := flagCandidates(flag)
+			if kind != test.kind || !reflect.DeepEqual(values, test.values) {
+				t.Errorf("got %q %v, want %q %v", kind, values, test.kind, test.values)
+			}
+		})
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	app := testCompletionApp(t)
+	tests := []struct {
+		shell string
+		write func(*bytes.Buffer)
+		want  []string
+	}{
+		{shell: "bash", write: func(b *bytes.Buffer) { writeBashCompletion(b, app) }, want: []string{
+			`"logs"|"config"|"config diff"|"config drift"|"lease"|"lease rm")`,
+			`"config diff --daemon"|"config diff -d") COMPREPLY=($(compgen -W "dhcp4 dhcp6" -- "$cur")); return ;;`,
+			`"lease rm --instance"|"lease rm -i") COMPREPLY=($(compgen -W "$(dhcli __complete instances 2>/dev/null)" -- "$cur")); return ;;`,
+			`*" --channel") COMPREPLY=($(compgen -W "production staging" -- "$cur")); return ;;`,
+		}},
+		{shell: "zsh", write: func(b *bytes.Buffer) { writeZshCompletion(b, app) }, want: []string{
+			`"config drift")`,
+			`dynamic=regions`,
+			`"logs --format"|"logs -f") compadd -- text json; return ;;`,
+		}},
+		{shell: "fish", write: func(b *bytes.Buffer) { writeFishCompletion(b, app) }, want: []string{
+			`case 'logs' 'config' 'config diff' 'config drift' 'lease' 'lease rm'`,
+			`complete -c dhcli -n '__dhcli_command_is "config"' -a diff`,
+			`complete -c dhcli -n '__dhcli_command_is "config diff"' -l daemon -s d -x -a 'dhcp4 dhcp6'`,
+			`complete -c dhcli -n '__dhcli_command_is "config drift"' -a '(dhcli __complete regions 2>/dev/null)'`,
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.shell, func(t *testing.T) {
+			var b bytes.Buffer
+			test.write(&b)
+			for _, want := range test.want {
+				if !strings.Contains(b.String(), want) {
+					t.Errorf("script lacks %s:\n%s", want, b.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRegionNames(t *testing.T) {
+	tests := []struct {
+		instances []string
+		want      []string
+	}{
+		{instances: []string{"NYC3", "NYC1", "S2R8", "AMS2"}, want: []string{"AMS", "NYC", "S2R"}},
+		{instances: []string{"LAB"}},
+		{instances: []string{"42"}},
+		{},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.instances, ","), func(t *testing.T) {
+			if got := regionNames(test.instances); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config is the optional dhcli configuration file, read from
+// $DHCLI_CONFIG or <user config dir>/dhcli/config.json.
+type Config struct {
+	Environments map[string]EnvironmentConfig `json:"environments"`
+	Diff         DiffConfig                   `json:"diff"`
+}
+
+// DiffConfig holds the settings for comparing Kea configurations.
+type DiffConfig struct {
+	// Ignore lists path patterns whose differences are expected, in the
+	// syntax of dhcli config diff --ignore.
+	Ignore []string `json:"ignore"`
+}
+
+// EnvironmentConfig holds the settings for a single Stork environment, keyed
+// by the environment name (e.g. "Production").
+type EnvironmentConfig struct {
+	// Credentials lists the credential providers to try, in order. See
+	// defaultCredentialProviders for the names and the default order.
+	Credentials []string `json:"credentials"`
+	// CredentialHelper is a git-style credential helper command.
+	CredentialHelper string      `json:"credentialHelper"`
+	Vault            VaultConfig `json:"vault"`
+	// KeaVault locates the Kea control agent credentials.
+	KeaVault VaultConfig `json:"keaVault"`
+}
+
+// VaultConfig locates a set of credentials in a Vault KV secret.
+type VaultConfig struct {
+	Address     string `json:"address"`
+	Path        string `json:"path"`
+	UserKey     string `json:"userKey"`
+	PasswordKey string `json:"passwordKey"`
+}
+
+func configPath() (string, error) {
+	if path, ok := os.LookupEnv("DHCLI_CONFIG"); ok {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, BinaryName, "config.json"), nil
+}
+
+// loadConfig reads the configuration file. A missing file is not an error.
+func loadConfig() (*Config, error) {
+	c := &Config{}
+	path, err := configPath()
+	if err != nil {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, c)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// environment returns the settings for the named environment.
+func (c *Config) environment(name string) EnvironmentConfig {
+	return c.Environments[name]
+}
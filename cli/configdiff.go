@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type ConfigDiffCmd struct {
+	InstanceA string   `kong:"arg='',name='instance-a',completion='instances',help='e.g. NYC3, S2R8'"`
+	InstanceB string   `kong:"arg='',name='instance-b',completion='instances',help='e.g. NYC4, S2R9'"`
+	Daemon    string   `kong:"optional,short='d',default='dhcp4',enum='dhcp4,dhcp6,d2,ca',help='Daemon whose configuration to compare: dhcp4, dhcp6, d2 or ca.'"`
+	Ignore    []string `kong:"optional,short='i',help='Ignore differences under a path pattern (repeatable). * matches within a key, ** across keys.'"`
+	NoDefault bool     `kong:"optional,name='no-default-ignores',help='Also report differences that are expected between instances, such as server names.'"`
+}
+
+// defaultDiffIgnores are differences expected between otherwise identical
+// instances, e.g. HA partners.
+var defaultDiffIgnores = []string{
+	"**.this-server-name",
+	"**.server-tag",
+	"**.server-hostname",
+}
+
+// listKeys names, per list, the fields identifying an element, tried in
+// order. Lists not named here are compared element by element.
+var listKeys = map[string][]string{
+	"subnet4":         {"id", "subnet"},
+	"subnet6":         {"id", "subnet"},
+	"shared-networks": {"name"},
+	"reservations":    {"hw-address", "duid", "client-id", "circuit-id", "flex-id", "ip-address"},
+	"option-data":     {"code", "name"},
+	"option-def":      {"code", "name"},
+	"pools":           {"pool"},
+	"pd-pools":        {"prefix"},
+	"client-classes":  {"name"},
+	"hooks-libraries": {"library"},
+	"loggers":         {"name"},
+	"peers":           {"name"},
+	"ddns-domains":    {"name"},
+	"tsig-keys":       {"name"},
+	"dns-servers":     {"ip-address"},
+	"output_options":  {"output"},
+	"output-options":  {"output"},
+}
+
+// ConfigChange is one semantic difference between two configurations. A is
+// nil for additions and B is nil for removals.
+type ConfigChange struct {
+	Path string      `json:"path"`
+	A    interface{} `json:"a,omitempty"`
+	B    interface{} `json:"b,omitempty"`
+}
+
+func (c ConfigChange) Kind() string {
+	switch {
+	case c.A == nil:
+		return "+"
+	case c.B == nil:
+		return "-"
+	}
+	return "~"
+}
+
+// diffConfig compares two configurations, matching list elements by the
+// fields in listKeys so that reordering is not reported as a change.
+func diffConfig(path string, a interface{}, b interface{}) []ConfigChange {
+	objectA, isObjectA := a.(map[string]interface{})
+	objectB, isObjectB := b.(map[string]interface{})
+	if isObjectA && isObjectB {
+		keys := map[string]bool{}
+		for key := range objectA {
+			keys[key] = true
+		}
+		for key := range objectB {
+			keys[key] = true
+		}
+
+		var changes []ConfigChange
+		for _, key := range sortedKeys(keys) {
+			changes = append(changes, diffValue(joinPath(path, key), key, objectA[key], objectB[key])...)
+		}
+		return changes
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return nil
+	}
+	return []ConfigChange{{Path: path, A: a, B: b}}
+}
+
+// diffValue compares the values of one object key.
+func diffValue(path string, key string, a interface{}, b interface{}) []ConfigChange {
+	if a == nil || b == nil {
+		if a == nil && b == nil {
+			return nil
+		}
+		return []ConfigChange{{Path: path, A: a, B: b}}
+	}
+
+	listA, isListA := a.([]interface{})
+	listB, isListB := b.([]interface{})
+	if !isListA || !isListB {
+		return diffConfig(path, a, b)
+	}
+
+	elementsA, orderA := keyedElements(key, listA)
+	elementsB, orderB := keyedElements(key, listB)
+
+	var changes []ConfigChange
+	for _, id := range orderA {
+		changes = append(changes, diffValue(path+id, "", elementsA[id], elementsB[id])...)
+	}
+	for _, id := range orderB {
+		if _, ok := elementsA[id]; !ok {
+			changes = append(changes, ConfigChange{Path: path + id, B: elementsB[id]})
+		}
+	}
+	return changes
+}
+
+// keyedElements indexes list elements by a selector such as [id=12] or
+// [subnet=10.0.0.0/24], so that a change's path can be passed to config show
+// --path. Elements without a usable key, or whose key is not unique, are
+// indexed by their position.
+func keyedElements(listName string, list []interface{}) (map[string]interface{}, []string) {
+	elements := make(map[string]interface{}, len(list))
+	order := make([]string, 0, len(list))
+	for i, item := range list {
+		id := fmt.Sprintf("[%d]", i)
+		if object, ok := item.(map[string]interface{}); ok {
+			for _, field := range listKeys[listName] {
+				value, ok := object[field]
+				if text := fmt.Sprint(value); ok && !strings.ContainsAny(text, "[]") {
+					id = fmt.Sprintf("[%s=%s]", field, text)
+					break
+				}
+			}
+		}
+		if _, duplicate := elements[id]; duplicate {
+			id = fmt.Sprintf("[%d]", i)
+		}
+		elements[id] = item
+		order = append(order, id)
+	}
+	return elements, order
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(keys map[string]bool) []string {
+	out := make([]string, 0, len(keys))
+	for key := range keys {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// compileIgnores turns path patterns into a single regexp. * matches within a
+// key or a [selector] and ** matches any number of keys.
+func compileIgnores(patterns []string) (*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	var alternatives []string
+	for _, pattern := range patterns {
+		var re strings.Builder
+		inBrackets := false
+		for i := 0; i < len(pattern); i++ {
+			switch {
+			case pattern[i] == '[' || pattern[i] == ']':
+				inBrackets = pattern[i] == '['
+				re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			case pattern[i] == '*' && inBrackets:
+				re.WriteString(`[^\]]*`)
+			case strings.HasPrefix(pattern[i:], "**."):
+				re.WriteString(`(?:.*\.)?`)
+				i += 2
+			case strings.HasPrefix(pattern[i:], "**"):
+				re.WriteString(`.*`)
+				i++
+			case pattern[i] == '*':
+				re.WriteString(`[^.\[]*`)
+			default:
+				re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		}
+		alternatives = append(alternatives, re.String())
+	}
+
+	// A pattern also covers everything below the path it matches.
+	return regexp.Compile(`^(?:` + strings.Join(alternatives, "|") + `)(?:$|[.\[])`)
+}
+
+func filterChanges(changes []ConfigChange, ignore *regexp.Regexp) []ConfigChange {
+	if ignore == nil {
+		return changes
+	}
+	var out []ConfigChange
+	for _, change := range changes {
+		if !ignore.MatchString(change.Path) {
+			out = append(out, change)
+		}
+	}
+	return out
+}
+
+// compactValue renders a value on one line for the change summary.
+func compactValue(v interface{}) string {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(out)
+}
+
+// printChanges writes one line per change, in diff style.
+func printChanges(changes []ConfigChange, nameA string, nameB string) {
+	for _, change := range changes {
+		switch change.Kind() {
+		case "+":
+			fmt.Printf("+ %s: only in %s: %s\n", change.Path, nameB, compactValue(change.B))
+		case "-":
+			fmt.Printf("- %s: only in %s: %s\n", change.Path, nameA, compactValue(change.A))
+		default:
+			fmt.Printf("~ %s: %s -> %s\n", change.Path, compactValue(change.A), compactValue(change.B))
+		}
+	}
+}
+
+// errConfigDiffers makes dhcli exit non-zero when configurations differ.
+var errConfigDiffers = errors.New("configurations differ")
+
+// diffIgnores combines the default, configured and command line ignore
+// patterns.
+func diffIgnores(extra []string, noDefault bool) (*regexp.Regexp, error) {
+	var patterns []string
+	if !noDefault {
+		patterns = append(patterns, defaultDiffIgnores...)
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	patterns = append(patterns, config.Diff.Ignore...)
+	patterns = append(patterns, extra...)
+
+	ignore, err := compileIgnores(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignore pattern: %w", err)
+	}
+	return ignore, nil
+}
+
+// fetchConfig fetches the scrubbed configuration of a daemon, resolving the
+// instance's environment and logging in to it.
+func fetchConfig(instance string, daemon string) (interface{}, error) {
+	envName, envURL, err := instanceEnvironment(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	jar, err := storkAuth(envURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", envName, err)
+	}
+
+	config, err := getDaemonConfig(instance, daemon, envURL, jar)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", envName, instance, err)
+	}
+	return scrubConfig(config.Config), nil
+}
+
+func (c *ConfigDiffCmd) Run() error {
+	ignore, err := diffIgnores(c.Ignore, c.NoDefault)
+	if err != nil {
+		return err
+	}
+
+	a, err := fetchConfig(c.InstanceA, c.Daemon)
+	if err != nil {
+		return err
+	}
+	b, err := fetchConfig(c.InstanceB, c.Daemon)
+	if err != nil {
+		return err
+	}
+
+	changes := filterChanges(diffConfig("", a, b), ignore)
+	if len(changes) == 0 {
+		fmt.Printf("%s and %s have the same %s configuration\n", c.InstanceA, c.InstanceB, c.Daemon)
+		return nil
+	}
+
+	printChanges(changes, c.InstanceA, c.InstanceB)
+	fmt.Printf("\n%d differences between %s and %s\n", len(changes), c.InstanceA, c.InstanceB)
+	return errConfigDiffers
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want []string
+	}{
+		{
+			name: "reordered subnets",
+			a:    `{"Dhcp4":{"subnet4":[{"id":1,"subnet":"10.0.0.0/24"},{"id":2,"subnet":"10.0.1.0/24"}]}}`,
+			b:    `{"Dhcp4":{"subnet4":[{"id":2,"subnet":"10.0.1.0/24"},{"id":1,"subnet":"10.0.0.0/24"}]}}`,
+		},
+		{
+			name: "changed option",
+			a:    `{"Dhcp4":{"subnet4":[{"id":12,"option-data":[{"code":6,"data":"10.0.0.2"},{"code":3,"data":"10.0.0.1"}]}]}}`,
+			b:    `{"Dhcp4":{"subnet4":[{"id":12,"option-data":[{"code":3,"data":"10.0.0.1"},{"code":6,"data":"10.0.0.3"}]}]}}`,
+			want: []string{"~ Dhcp4.subnet4[id=12].option-data[code=6].data"},
+		},
+		{
+			name: "added and removed",
+			a:    `{"Dhcp4":{"subnet4":[{"id":1},{"id":13}],"valid-lifetime":4000}}`,
+			b:    `{"Dhcp4":{"subnet4":[{"id":14},{"id":1}],"renew-timer":1000}}`,
+			want: []string{
+				"+ Dhcp4.renew-timer",
+				"- Dhcp4.subnet4[id=13]",
+				"+ Dhcp4.subnet4[id=14]",
+				"- Dhcp4.valid-lifetime",
+			},
+		},
+		{
+			name: "keyed by prefix",
+			a:    `{"Dhcp4":{"subnet4":[{"subnet":"10.0.0.0/24","pools":[{"pool":"10.0.0.10-10.0.0.20"}]}]}}`,
+			b:    `{"Dhcp4":{"subnet4":[{"subnet":"10.0.0.0/24","pools":[{"pool":"10.0.0.10-10.0.0.30"}]}]}}`,
+			want: []string{
+				"- Dhcp4.subnet4[subnet=10.0.0.0/24].pools[pool=10.0.0.10-10.0.0.20]",
+				"+ Dhcp4.subnet4[subnet=10.0.0.0/24].pools[pool=10.0.0.10-10.0.0.30]",
+			},
+		},
+		{
+			name: "unkeyed list by position",
+			a:    `{"Dhcp4":{"interfaces-config":{"interfaces":["eth0","eth1"]}}}`,
+			b:    `{"Dhcp4":{"interfaces-config":{"interfaces":["eth0","eth2"]}}}`,
+			want: []string{"~ Dhcp4.interfaces-config.interfaces[1]"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, change := range diffConfig("", testConfig(t, test.a), testConfig(t, test.b)) {
+				got = append(got, change.Kind()+" "+change.Path)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// Every path keyedElements produces must select its element again.
+func TestKeyedElementsSelectable(t *testing.T) {
+	tests := []struct {
+		name string
+		list string
+		want []string
+	}{
+		{name: "by id", list: "subnet4", want: []string{"[id=12]", "[id=13]"}},
+		{name: "by prefix", list: "subnet4", want: []string{"[subnet=10.0.0.0/24]", "[subnet=10.0.1.0/24]"}},
+		{name: "duplicates by position", list: "subnet4", want: []string{"[id=12]", "[1]"}},
+		{name: "brackets by position", list: "client-classes", want: []string{"[0]", "[name=b]"}},
+		{name: "unkeyed", list: "relay", want: []string{"[0]", "[1]"}},
+	}
+	lists := map[string]string{
+		"by id":                  `[{"id":12,"subnet":"10.0.0.0/24"},{"id":13,"subnet":"10.0.1.0/24"}]`,
+		"by prefix":              `[{"subnet":"10.0.0.0/24"},{"subnet":"10.0.1.0/24"}]`,
+		"duplicates by position": `[{"id":12,"subnet":"10.0.0.0/24"},{"id":12,"subnet":"10.0.1.0/24"}]`,
+		"brackets by position":   `[{"name":"a[1]"},{"name":"b"}]`,
+		"unkeyed":                `[{"ip-addresses":["10.0.0.1"]},{"ip-addresses":["10.0.0.2"]}]`,
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list := testConfig(t, lists[test.name]).([]interface{})
+			elements, order := keyedElements(test.list, list)
+			if !reflect.DeepEqual(order, test.want) {
+				t.Fatalf("got %q, want %q", order, test.want)
+			}
+
+			config := map[string]interface{}{"Dhcp4": map[string]interface{}{test.list: list}}
+			for _, id := range order {
+				got, err := selectPath(config, "Dhcp4."+test.list+id)
+				if err != nil {
+					t.Fatalf("%s: %v", id, err)
+				}
+				if !reflect.DeepEqual(got, elements[id]) {
+					t.Errorf("%s selects %v, want %v", id, got, elements[id])
+				}
+			}
+		})
+	}
+}
+
+func TestCompileIgnores(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "**.this-server-name", path: "Dhcp4.hooks-libraries[library=ha.so].parameters.high-availability[0].this-server-name", want: true},
+		{pattern: "**.server-tag", path: "Dhcp4.server-tag", want: true},
+		{pattern: "**.server-tag", path: "Dhcp4.server-tagged", want: false},
+		{pattern: "Dhcp4.subnet4[id=*].pools", path: "Dhcp4.subnet4[id=12].pools[pool=10.0.0.10-10.0.0.20]", want: true},
+		{pattern: "Dhcp4.*.pools", path: "Dhcp4.subnet4[id=12].pools", want: false},
+		{pattern: "Dhcp4.subnet4", path: "Dhcp4.subnet4[subnet=10.0.0.0/24].id", want: true},
+		{pattern: "Dhcp4.subnet4", path: "Dhcp4.subnet4-extra", want: false},
+		{pattern: "Dhcp4.valid-*", path: "Dhcp4.valid-lifetime", want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.path, func(t *testing.T) {
+			ignore, err := compileIgnores([]string{test.pattern})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ignore.MatchString(test.path); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if ignore, err := compileIgnores(nil); ignore != nil || err != nil {
+		t.Errorf("no patterns: got %v, %v", ignore, err)
+	}
+}
@@ -0,0 +1,401 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ConflictsCmd struct {
+	Region string `kong:"arg='',optional,name='region',completion='regions',help='Only check reservations of this Kea instance or prefix, e.g. NYC3'"`
+}
+
+// Kinds of conflict reported by dhcli conflicts.
+const (
+	conflictReservedIP     = "reserved IP leased to other client"
+	conflictDynamicLease   = "reserved MAC holds dynamic lease"
+	conflictMultipleLeases = "MAC leased on multiple instances"
+	conflictDuplicateHost  = "reservation in config and host database"
+	conflictHostMismatch   = "reservation differs between instances"
+)
+
+type Conflict struct {
+	Kind      string
+	EnvName   string
+	Instance  string
+	IpAddress string
+	HwAddress string
+	Details   string
+}
+
+// Address returns the reserved address without a prefix length.
+func (h *KeaHost) Address() string {
+	if len(h.AddressReservations) == 0 {
+		return ""
+	}
+	address, _, _ := strings.Cut(h.AddressReservations[0].Address, "/")
+	return address
+}
+
+// HwAddress returns the MAC address the host is reserved for, or "" when it
+// is identified otherwise.
+func (h *KeaHost) HwAddress() string {
+	for _, id := range h.HostIdentifiers {
+		if id.IdType == "hw-address" {
+			return strings.ToLower(id.IdHexValue)
+		}
+	}
+	return ""
+}
+
+// Instances lists the Kea instances the reservation is configured on.
+func (h *KeaHost) Instances() string {
+	var names []string
+	for _, local := range h.LocalHosts {
+		names = append(names, local.AppName)
+	}
+	return strings.Join(names, ", ")
+}
+
+// hostConflicts reports reservations Stork sees twice on one instance, from
+// the configuration file and the host database, or with different hostnames
+// on different instances.
+func hostConflicts(envName string, host KeaHost) []Conflict {
+	var conflicts []Conflict
+	sources := map[string]map[string]bool{}
+	hostnames := map[string]bool{}
+	for _, local := range host.LocalHosts {
+		if sources[local.AppName] == nil {
+			sources[local.AppName] = map[string]bool{}
+		}
+		sources[local.AppName][local.DataSource] = true
+		hostnames[local.Hostname] = true
+	}
+
+	for appName, appSources := range sources {
+		if len(appSources) > 1 {
+			conflicts = append(conflicts, Conflict{
+				Kind: conflictDuplicateHost, EnvName: envName, Instance: appName,
+				IpAddress: host.Address(), HwAddress: host.HwAddress(),
+			})
+		}
+	}
+	if len(hostnames) > 1 {
+		var names []string
+		for name := range hostnames {
+			names = append(names, strconv.Quote(name))
+		}
+		sort.Strings(names)
+		conflicts = append(conflicts, Conflict{
+			Kind: conflictHostMismatch, EnvName: envName, Instance: host.Instances(),
+			IpAddress: host.Address(), HwAddress: host.HwAddress(),
+			Details: "hostnames " + strings.Join(names, ", "),
+		})
+	}
+	return conflicts
+}
+
+// heldLease is an active lease as held by one Kea instance.
+type heldLease struct {
+	EnvName  string
+	Instance string
+	Lease    KeaLease
+}
+
+// Client names the client holding the lease: its MAC, or else its client ID
+// or DUID.
+func (l heldLease) Client() string {
+	switch {
+	case l.Lease.HwAddress != "":
+		return l.Lease.HwAddress
+	case l.Lease.ClientID != "":
+		return "client-id " + l.Lease.ClientID
+	}
+	return "duid " + l.Lease.Duid
+}
+
+// leaseIndex holds the active leases of every Kea instance by address and by
+// MAC address, so reservations are checked without a search each.
+type leaseIndex struct {
+	byIP  map[string][]heldLease
+	byMAC map[string][]heldLease
+}
+
+func newLeaseIndex() *leaseIndex {
+	return &leaseIndex{byIP: map[string][]heldLease{}, byMAC: map[string][]heldLease{}}
+}
+
+func (x *leaseIndex) add(envName string, instance string, lease KeaLease) {
+	if lease.State != leaseDefault {
+		return
+	}
+	held := heldLease{EnvName: envName, Instance: instance, Lease: lease}
+	x.byIP[lease.IpAddress] = append(x.byIP[lease.IpAddress], held)
+	if lease.HwAddress != "" {
+		mac := strings.ToLower(lease.HwAddress)
+		x.byMAC[mac] = append(x.byMAC[mac], held)
+	}
+}
+
+// indexLeases pages through the leases of every Kea instance in the
+// environments through their control agents. An instance that can't be read
+// is reported and left out.
+func indexLeases(envs []stork) *leaseIndex {
+	index := newLeaseIndex()
+	for _, env := range envs {
+		apps, err := getAppDetails(env.url, env.jar)
+		if err != nil {
+			fmt.Printf("%s: %s\n", env.name, err.Error())
+			continue
+		}
+		for i := range apps {
+			app := &apps[i]
+			app.envName = env.name
+			for _, daemon := range app.Details.Daemons {
+				if daemon.Name != "dhcp4" && daemon.Name != "dhcp6" {
+					continue
+				}
+				err := getLeasePages(app, daemon.Name, func(leases []KeaLease) error {
+					for _, lease := range leases {
+						index.add(env.name, app.Name, lease)
+					}
+					return nil
+				})
+				if err != nil {
+					fmt.Printf("%s: %s: %s\n", env.name, app.Name, err.Error())
+				}
+			}
+		}
+	}
+	return index
+}
+
+// normalizeHex strips the separators from a hex identifier.
+func normalizeHex(id string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", "-", "", ".", "").Replace(id))
+}
+
+// reservedFor reports whether a lease belongs to the host, comparing the
+// identifiers both carry. ok is false if they have none in common to compare,
+// e.g. for a reservation by circuit-id.
+func (h *KeaHost) reservedFor(lease KeaLease) (owner bool, ok bool) {
+	for _, id := range h.HostIdentifiers {
+		var leaseID string
+		switch id.IdType {
+		case "hw-address":
+			leaseID = lease.HwAddress
+		case "client-id":
+			leaseID = lease.ClientID
+		case "duid":
+			leaseID = lease.Duid
+		}
+		if leaseID == "" {
+			continue
+		}
+		if normalizeHex(leaseID) == normalizeHex(id.IdHexValue) {
+			return true, true
+		}
+		ok = true
+	}
+	return false, ok
+}
+
+// leaseConflicts compares a reservation with the leases of its address in
+// its environment and of its MAC address in every environment.
+func leaseConflicts(envName string, host KeaHost, leases *leaseIndex) []Conflict {
+	var conflicts []Conflict
+	address, mac := host.Address(), host.HwAddress()
+	if address == "" {
+		return nil
+	}
+
+	// HA partners hold copies of the same lease.
+	reported := map[string]bool{}
+	for _, held := range leases.byIP[address] {
+		owner, ok := host.reservedFor(held.Lease)
+		if held.EnvName != envName || owner || !ok || reported[held.Client()] {
+			continue
+		}
+		reported[held.Client()] = true
+		conflicts = append(conflicts, Conflict{
+			Kind: conflictReservedIP, EnvName: envName, Instance: held.Instance,
+			IpAddress: address, HwAddress: mac,
+			Details: fmt.Sprintf("leased to %s (%s)", held.Client(), held.Lease.Hostname),
+		})
+	}
+	if mac == "" {
+		return conflicts
+	}
+
+	// HA partners hold copies of the same dynamic lease.
+	held := map[string][]string{}
+	var addresses []string
+	for _, lease := range leases.byMAC[mac] {
+		ip := lease.Lease.IpAddress
+		if ip == address {
+			continue
+		}
+		if held[ip] == nil {
+			addresses = append(addresses, ip)
+		}
+		held[ip] = append(held[ip], fmt.Sprintf("%s/%s", lease.EnvName, lease.Instance))
+	}
+	sort.Strings(addresses)
+	for _, ip := range addresses {
+		conflicts = append(conflicts, Conflict{
+			Kind: conflictDynamicLease, EnvName: envName, Instance: strings.Join(held[ip], ", "),
+			IpAddress: address, HwAddress: mac,
+			Details: fmt.Sprintf("holds %s", ip),
+		})
+	}
+	return conflicts
+}
+
+// multipleLeaseConflicts reports every MAC address holding leases for
+// different addresses, reserved or not. HA partners hold copies of the same
+// lease, so only distinct addresses count. With a region, only MACs holding
+// a lease on one of its instances are reported.
+func multipleLeaseConflicts(leases *leaseIndex, region string) []Conflict {
+	var macs []string
+	for mac := range leases.byMAC {
+		macs = append(macs, mac)
+	}
+	sort.Strings(macs)
+
+	var conflicts []Conflict
+	for _, mac := range macs {
+		held := map[string][]string{}
+		envs := map[string]bool{}
+		instances := map[string]bool{}
+		inRegion := region == ""
+		for _, lease := range leases.byMAC[mac] {
+			where := fmt.Sprintf("%s/%s", lease.EnvName, lease.Instance)
+			held[lease.Lease.IpAddress] = append(held[lease.Lease.IpAddress], where)
+			envs[lease.EnvName] = true
+			instances[lease.Instance] = true
+			if region != "" && matchesInstance(lease.Instance, region) {
+				inRegion = true
+			}
+		}
+		if len(held) < 2 || !inRegion {
+			continue
+		}
+
+		var addresses []string
+		for ip := range held {
+			addresses = append(addresses, ip)
+		}
+		sort.Strings(addresses)
+		var descriptions []string
+		for _, ip := range addresses {
+			descriptions = append(descriptions, fmt.Sprintf("%s on %s", ip, strings.Join(held[ip], ", ")))
+		}
+
+		conflicts = append(conflicts, Conflict{
+			Kind: conflictMultipleLeases, EnvName: strings.Join(sortedKeys(envs), ", "),
+			Instance: strings.Join(sortedKeys(instances), ", "), HwAddress: mac,
+			Details: strings.Join(descriptions, "; "),
+		})
+	}
+	return conflicts
+}
+
+// getHosts fetches the reservations of the instances matching region, or of
+// the whole environment.
+func getHosts(region string, envURL *url.URL, jar *cookiejar.Jar) ([]KeaHost, error) {
+	if region == "" {
+		return getAllPages[KeaHost]("/api/hosts", url.Values{}, envURL, jar)
+	}
+
+	instances, err := selectInstances(region, envURL, jar)
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []KeaHost
+	seen := map[int]bool{}
+	for _, instance := range instances {
+		page, err := retryStale(envURL, func() ([]KeaHost, error) {
+			appID, err := getAppID(instance, envURL, jar)
+			if err != nil {
+				return nil, err
+			}
+			query := url.Values{}
+			query.Set("appId", strconv.Itoa(appID))
+			return getAllPages[KeaHost]("/api/hosts", query, envURL, jar)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range page {
+			if !seen[host.ID] {
+				seen[host.ID] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts, nil
+}
+
+func (c *ConflictsCmd) Run() error {
+	envs, err := storkLogin()
+	if err != nil {
+		return err
+	}
+
+	regionEnv := ""
+	if c.Region != "" {
+		name, _, err := instanceEnvironment(c.Region)
+		if err != nil {
+			return err
+		}
+		regionEnv = name
+	}
+
+	// A reserved MAC may hold leases in any environment, so every instance's
+	// leases are read, once.
+	leases := indexLeases(envs)
+
+	var conflicts []Conflict
+	for _, env := range envs {
+		if regionEnv != "" && env.name != regionEnv {
+			continue
+		}
+
+		hosts, err := getHosts(c.Region, env.url, env.jar)
+		if err != nil {
+			fmt.Printf("%s: %s\n", env.name, err.Error())
+			continue
+		}
+
+		for _, host := range hosts {
+			conflicts = append(conflicts, hostConflicts(env.name, host)...)
+			conflicts = append(conflicts, leaseConflicts(env.name, host, leases)...)
+		}
+	}
+	conflicts = append(conflicts, multipleLeaseConflicts(leases, c.Region)...)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetBorder(false)
+	table.SetHeader([]string{"Conflict", "Environment", "Kea Instance", "Reserved IP", "Reserved MAC", "Details"})
+	for _, conflict := range conflicts {
+		table.Append([]string{
+			conflict.Kind,
+			conflict.EnvName,
+			conflict.Instance,
+			conflict.IpAddress,
+			conflict.HwAddress,
+			conflict.Details,
+		})
+	}
+	fmt.Printf("\n(%d conflicts)\n", len(conflicts))
+	table.Render()
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testHost(t *testing.T, text string) KeaHost {
+	host := KeaHost{}
+	if err := json.Unmarshal([]byte(text), &host); err != nil {
+		t.Fatal(err)
+	}
+	return host
+}
+
+func TestLeaseConflicts(t *testing.T) {
+	byMAC := testHost(t, `{"addressReservations":[{"address":"10.0.0.5/32"}],
+		"hostIdentifiers":[{"idType":"hw-address","idHexValue":"AA:BB:CC:00:00:01"}],
+		"localHosts":[{"appName":"NYC3"},{"appName":"NYC4"}]}`)
+	byClientID := testHost(t, `{"addressReservations":[{"address":"10.0.0.6"}],
+		"hostIdentifiers":[{"idType":"client-id","idHexValue":"01:aa:bb:cc:00:00:02"}],
+		"localHosts":[{"appName":"NYC3"}]}`)
+	byCircuit := testHost(t, `{"addressReservations":[{"address":"10.0.0.7"}],
+		"hostIdentifiers":[{"idType":"circuit-id","idHexValue":"01:02"}],
+		"localHosts":[{"appName":"NYC3"}]}`)
+
+	lease := func(ip, mac, clientID string, state int) KeaLease {
+		return KeaLease{IpAddress: ip, HwAddress: mac, ClientID: clientID, State: state, Hostname: "h"}
+	}
+	type held struct {
+		env, instance string
+		lease         KeaLease
+	}
+	tests := []struct {
+		name   string
+		host   KeaHost
+		leases []held
+		want   []string
+	}{
+		{name: "own lease on both partners", host: byMAC, leases: []held{
+			{"Production", "NYC3", lease("10.0.0.5", "aa:bb:cc:00:00:01", "", leaseDefault)},
+			{"Production", "NYC4", lease("10.0.0.5", "aa:bb:cc:00:00:01", "", leaseDefault)},
+		}},
+		{name: "reserved IP leased to another MAC", host: byMAC, leases: []held{
+			{"Production", "NYC3", lease("10.0.0.5", "aa:bb:cc:00:00:09", "", leaseDefault)},
+			{"Production", "NYC4", lease("10.0.0.5", "aa:bb:cc:00:00:09", "", leaseDefault)},
+		}, want: []string{conflictReservedIP + " NYC3 leased to aa:bb:cc:00:00:09 (h)"}},
+		{name: "other environment ignored", host: byMAC, leases: []held{
+			{"Stage2", "S2R8", lease("10.0.0.5", "aa:bb:cc:00:00:09", "", leaseDefault)},
+		}},
+		{name: "expired lease ignored", host: byMAC, leases: []held{
+			{"Production", "NYC3", lease("10.0.0.5", "aa:bb:cc:00:00:09", "", leaseReclaimed)},
+		}},
+		{name: "dynamic lease elsewhere", host: byMAC, leases: []held{
+			{"Production", "NYC3", lease("10.0.0.5", "aa:bb:cc:00:00:01", "", leaseDefault)},
+			{"Stage2", "S2R8", lease("10.9.0.5", "AA:BB:CC:00:00:01", "", leaseDefault)},
+		}, want: []string{conflictDynamicLease + " Stage2/S2R8 holds 10.9.0.5"}},
+		{name: "dynamic lease on both partners", host: byMAC, leases: []held{
+			{"Production", "NYC3", lease("10.0.0.9", "aa:bb:cc:00:00:01", "", leaseDefault)},
+			{"Production", "NYC4", lease("10.0.0.9", "aa:bb:cc:00:00:01", "", leaseDefault)},
+		}, want: []string{conflictDynamicLease + " Production/NYC3, Production/NYC4 holds 10.0.0.9"}},
+		{name: "client-id matches", host: byClientID, leases: []held{
+			{"Production", "NYC3", lease("10.0.0.6", "aa:bb:cc:00:00:09", "01:AA:BB:CC:00:00:02", leaseDefault)},
+		}},
+		{name: "client-id differs", host: byClientID, leases: []held{
+			{"Production", "NYC3", lease("10.0.0.6", "aa:bb:cc:00:00:09", "01:aa:bb:cc:00:00:03", leaseDefault)},
+		}, want: []string{conflictReservedIP + " NYC3 leased to aa:bb:cc:00:00:09 (h)"}},
+		{name: "nothing to compare", host: byCircuit, leases: []held{
+			{"Production", "NYC3", lease("10.0.0.7", "aa:bb:cc:00:00:09", "", leaseDefault)},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			index := newLeaseIndex()
+			for _, h := range test.leases {
+				index.add(h.env, h.instance, h.lease)
+			}
+			var got []string
+			for _, c := range leaseConflicts("Production", test.host, index) {
+				got = append(got, c.Kind+" "+c.Instance+" "+c.Details)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMultipleLeaseConflicts(t *testing.T) {
+	type held struct {
+		env, instance, ip, mac string
+	}
+	tests := []struct {
+		name   string
+		region string
+		leases []held
+		want   []string
+	}{
+		{name: "copies on HA partners", leases: []held{
+			{"Production", "NYC3", "10.0.0.5", "aa:bb:cc:00:00:01"},
+			{"Production", "NYC4", "10.0.0.5", "aa:bb:cc:00:00:01"},
+		}},
+		{name: "unreserved MAC on two instances", leases: []held{
+			{"Production", "NYC3", "10.0.0.5", "aa:bb:cc:00:00:01"},
+			{"Production", "NYC4", "10.0.0.5", "aa:bb:cc:00:00:01"},
+			{"Production", "SFO1", "10.8.0.5", "AA:BB:CC:00:00:01"},
+		}, want: []string{
+			conflictMultipleLeases + " Production NYC3, NYC4, SFO1 aa:bb:cc:00:00:01 10.0.0.5 on Production/NYC3, Production/NYC4; 10.8.0.5 on Production/SFO1",
+		}},
+		{name: "across environments", leases: []held{
+			{"Production", "NYC3", "10.0.0.5", "aa:bb:cc:00:00:01"},
+			{"Stage2", "S2R8", "10.9.0.5", "aa:bb:cc:00:00:01"},
+			{"Production", "NYC3", "10.0.0.6", "aa:bb:cc:00:00:02"},
+		}, want: []string{
+			conflictMultipleLeases + " Production, Stage2 NYC3, S2R8 aa:bb:cc:00:00:01 10.0.0.5 on Production/NYC3; 10.9.0.5 on Stage2/S2R8",
+		}},
+		{name: "region holds one of the leases", region: "s2", leases: []held{
+			{"Production", "NYC3", "10.0.0.5", "aa:bb:cc:00:00:01"},
+			{"Stage2", "S2R8", "10.9.0.5", "aa:bb:cc:00:00:01"},
+		}, want: []string{
+			conflictMultipleLeases + " Production, Stage2 NYC3, S2R8 aa:bb:cc:00:00:01 10.0.0.5 on Production/NYC3; 10.9.0.5 on Stage2/S2R8",
+		}},
+		{name: "region holds none", region: "SFO", leases: []held{
+			{"Production", "NYC3", "10.0.0.5", "aa:bb:cc:00:00:01"},
+			{"Stage2", "S2R8", "10.9.0.5", "aa:bb:cc:00:00:01"},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			index := newLeaseIndex()
+			for _, h := range test.leases {
+				index.add(h.env, h.instance, KeaLease{IpAddress: h.ip, HwAddress: h.mac, State: leaseDefault})
+			}
+			var got []string
+			for _, c := range multipleLeaseConflicts(index, test.region) {
+				got = append(got, c.Kind+" "+c.EnvName+" "+c.Instance+" "+c.HwAddress+" "+c.Details)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHostConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want []string
+	}{
+		{name: "consistent", host: `{"localHosts":[{"appName":"NYC3","dataSource":"config","hostname":"a"},{"appName":"NYC4","dataSource":"config","hostname":"a"}]}`},
+		{name: "config and database", host: `{"localHosts":[{"appName":"NYC3","dataSource":"config","hostname":"a"},{"appName":"NYC3","dataSource":"api","hostname":"a"}]}`,
+			want: []string{conflictDuplicateHost + " NYC3 "}},
+		{name: "hostnames differ", host: `{"localHosts":[{"appName":"NYC3","dataSource":"config","hostname":"b"},{"appName":"NYC4","dataSource":"config","hostname":"a"}]}`,
+			want: []string{conflictHostMismatch + ` NYC3, NYC4 hostnames "a", "b"`}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, c := range hostConflicts("Production", testHost(t, test.host)) {
+				got = append(got, c.Kind+" "+c.Instance+" "+c.Details)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Credentials are a user and password, e.g. the Stork login for an
+// environment.
+type Credentials struct {
+	User     string
+	Password string
+}
+
+// credentialKind is one set of credentials dhcli looks up per environment:
+// the Stork login, or the basic auth of the environment's Kea control agents.
+type credentialKind struct {
+	// Label names the credentials in prompts.
+	Label string
+	// UserVar and PasswordVar are read by the env provider.
+	UserVar     string
+	PasswordVar string
+	// AccountSuffix is added to the environment name to form the keyring
+	// account.
+	AccountSuffix string
+}
+
+var (
+	storkCredentials = credentialKind{Label: "Stork", UserVar: "STORK_USER", PasswordVar: "STORK_PASS"}
+	keaCredentials   = credentialKind{Label: "Kea control agent", UserVar: "DHCLI_KEA_USER", PasswordVar: "DHCLI_KEA_PASSWORD", AccountSuffix: "/kea"}
+)
+
+// CredentialProvider looks up credentials for an environment. A
+// provider that has nothing to offer returns nil credentials and no error so
+// the next provider in the chain is tried.
+type CredentialProvider interface {
+	Name() string
+	Credentials(envName string, envURL *url.URL) (*Credentials, error)
+}
+
+// interactive allows the prompt provider to ask for credentials. It is
+// turned off where a prompt would hang, such as shell completion.
+var interactive = true
+
+// defaultCredentialProviders is the chain used when an environment does not
+// configure one.
+var defaultCredentialProviders = []string{"env", "helper", "vault", "keyring", "prompt"}
+
+// credentialProviders builds the provider chain for one kind of credentials
+// of an environment.
+func credentialProviders(env EnvironmentConfig, kind credentialKind) ([]CredentialProvider, error) {
+	names := env.Credentials
+	if len(names) == 0 {
+		names = defaultCredentialProviders
+	}
+	vault := env.Vault
+	if kind == keaCredentials {
+		vault = env.KeaVault
+	}
+
+	var providers []CredentialProvider
+	for _, name := range names {
+		switch name {
+		case "env":
+			providers = append(providers, envProvider{UserVar: kind.UserVar, PasswordVar: kind.PasswordVar})
+		case "prompt":
+			providers = append(providers, promptProvider{Label: kind.Label})
+		case "keyring":
+			providers = append(providers, keyringProvider{AccountSuffix: kind.AccountSuffix})
+		case "helper":
+			providers = append(providers, helperProvider{Command: env.CredentialHelper})
+		case "vault":
+			providers = append(providers, vaultProvider{Config: vault})
+		default:
+			return nil, fmt.Errorf("unknown credential provider %q", name)
+		}
+	}
+	return providers, nil
+}
+
+// lookupCredentials walks the provider chain for an environment and returns
+// the first Stork credentials found.
+func lookupCredentials(envName string, envURL *url.URL) (*Credentials, error) {
+	creds, err := lookupKind(storkCredentials, envName, envURL)
+	if err != nil || creds != nil {
+		return creds, err
+	}
+	return nil, errors.New("no Stork credentials found! Please define\n" +
+		"'STORK_USER' and 'STORK_PASS' in your shell\n" +
+		"with values from Vault: stork-dhcp/tools\n" +
+		"or configure a credential provider (see README)")
+}
+
+// lookupKind walks the provider chain for one kind of credentials of an
+// environment. It returns nil credentials if no provider has any.
+func lookupKind(kind credentialKind, envName string, envURL *url.URL) (*Credentials, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	providers, err := credentialProviders(config.environment(envName), kind)
+	if err != nil {
+		return nil, err
+	}
+	return firstCredentials(providers, envName, envURL)
+}
+
+// firstCredentials returns the credentials of the first provider in the
+// chain that has any. An error stops the chain.
+func firstCredentials(providers []CredentialProvider, envName string, envURL *url.URL) (*Credentials, error) {
+	for _, provider := range providers {
+		creds, err := provider.Credentials(envName, envURL)
+		if err != nil {
+			return nil, fmt.Errorf("%s credential provider: %w", provider.Name(), err)
+		}
+		if creds != nil {
+			return creds, nil
+		}
+	}
+	return nil, nil
+}
+
+// envProvider reads a pair of environment variables, e.g. STORK_USER and
+// STORK_PASS.
+type envProvider struct {
+	UserVar     string
+	PasswordVar string
+}
+
+func (envProvider) Name() string { return "env" }
+
+func (e envProvider) Credentials(string, *url.URL) (*Credentials, error) {
+	user, ok := os.LookupEnv(e.UserVar)
+	if !ok {
+		return nil, nil
+	}
+	password, ok := os.LookupEnv(e.PasswordVar)
+	if !ok {
+		return nil, nil
+	}
+	return &Credentials{User: user, Password: password}, nil
+}
+
+// promptProvider asks for credentials when stdin is a terminal.
+type promptProvider struct {
+	Label string
+}
+
+func (promptProvider) Name() string { return "prompt" }
+
+func (p promptProvider) Credentials(envName string, _ *url.URL) (*Credentials, error) {
+	if !interactive || !isTerminal(os.Stdin) || runtime.GOOS == "windows" {
+		return nil, nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Fprintf(os.Stderr, "%s %s user: ", p.Label, envName)
+	user, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %s password: ", p.Label, envName)
+	if err = stty("-echo"); err != nil {
+		return nil, err
+	}
+	password, err := reader.ReadString('\n')
+	_ = stty("echo")
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credentials{
+		User:     strings.TrimSpace(user),
+		Password: strings.TrimRight(password, "\r\n"),
+	}, nil
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// stty changes the terminal attached to stdin.
+func stty(args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+// keyringProvider reads credentials from the macOS keychain or the freedesktop
+// secret service. The secret is stored as "<user>:<password>" under the
+// service "dhcli" with the environment name as the account, plus "/kea" for
+// the control agent credentials, e.g.:
+//
+//	security add-generic-password -s dhcli -a Production -w 'me@example.com:secret'
+//	secret-tool store --label=dhcli service dhcli account Production/kea
+type keyringProvider struct {
+	AccountSuffix string
+}
+
+func (keyringProvider) Name() string { return "keyring" }
+
+func (k keyringProvider) Credentials(envName string, _ *url.URL) (*Credentials, error) {
+	account := envName + k.AccountSuffix
+	var name string
+	var args []string
+	switch runtime.GOOS {
+	case "darwin":
+		name, args = "security", []string{"find-generic-password", "-s", BinaryName, "-a", account, "-w"}
+	case "linux", "freebsd", "openbsd":
+		name, args = "secret-tool", []string{"lookup", "service", BinaryName, "account", account}
+	default:
+		return nil, nil
+	}
+
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, nil
+	}
+
+	// Both tools exit non-zero when there is no matching item.
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return nil, nil
+	}
+
+	user, password, found := strings.Cut(strings.TrimRight(string(out), "\r\n"), ":")
+	if !found {
+		return nil, errors.New("keyring secret is not in '<user>:<password>' form")
+	}
+	return &Credentials{User: user, Password: password}, nil
+}
+
+// helperProvider runs a git-style credential helper: "<command> get" is
+// run through the shell with the request on stdin, and the helper answers
+// with username= and password= lines.
+type helperProvider struct {
+	Command string
+}
+
+func (helperProvider) Name() string { return "helper" }
+
+func (h helperProvider) Credentials(_ string, envURL *url.URL) (*Credentials, error) {
+	if h.Command == "" {
+		return nil, nil
+	}
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.Command(shell, flag, h.Command+" get")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=%s\nhost=%s\n\n", envURL.Scheme, envURL.Host))
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	creds := &Credentials{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "username":
+			creds.User = value
+		case "password":
+			creds.Password = value
+		}
+	}
+	if creds.User == "" || creds.Password == "" {
+		return nil, nil
+	}
+	return creds, nil
+}
+
+// vaultProvider reads credentials from a Vault KV (v1 or v2) secret using the
+// token in VAULT_TOKEN or ~/.vault-token.
+type vaultProvider struct {
+	Config VaultConfig
+}
+
+func (vaultProvider) Name() string { return "vault" }
+
+func (v vaultProvider) Credentials(string, *url.URL) (*Credentials, error) {
+	address := v.Config.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" || v.Config.Path == "" {
+		return nil, nil
+	}
+
+	token := vaultToken()
+	if token == "" {
+		return nil, nil
+	}
+
+	userKey, passwordKey := v.Config.UserKey, v.Config.PasswordKey
+	if userKey == "" {
+		userKey = "username"
+	}
+	if passwordKey == "" {
+		passwordKey = "password"
+	}
+
+	req, err := http.NewRequest("GET", strings.TrimRight(address, "/")+"/v1/"+strings.TrimLeft(v.Config.Path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// A missing secret leaves the lookup to the next provider.
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("vault returned %d reading %s", resp.StatusCode, v.Config.Path)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	err = json.Unmarshal(body, &secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// KV v2 nests the secret under data.data.
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	user, _ := data[userKey].(string)
+	password, _ := data[passwordKey].(string)
+	if user == "" || password == "" {
+		return nil, fmt.Errorf("secret %s has no %q/%q keys", v.Config.Path, userKey, passwordKey)
+	}
+	return &Credentials{User: user, Password: password}, nil
+}
+
+func vaultToken() string {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".vault-token"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
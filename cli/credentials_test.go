@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeVault serves one KV secret to requests carrying the right token.
+func fakeVault(t *testing.T, path string, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("X-Vault-Token") != "good-token":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+		case r.URL.Path != "/v1/"+path:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		default:
+			w.Write([]byte(body))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVaultProvider(t *testing.T) {
+	kv2 := fakeVault(t, "secret/data/stork", `{"data":{"data":{"username":"stork","password":"hunter2"},"metadata":{"version":3}}}`)
+	kv1 := fakeVault(t, "kv/stork", `{"data":{"user":"stork1","pass":"hunter1"}}`)
+	incomplete := fakeVault(t, "secret/data/stork", `{"data":{"data":{"username":"stork"}}}`)
+
+	tests := []struct {
+		name   string
+		config VaultConfig
+		token  string
+		want   *Credentials
+		err    string
+	}{
+		{name: "kv v2", config: VaultConfig{Address: kv2.URL, Path: "secret/data/stork"}, token: "good-token", want: &Credentials{User: "stork", Password: "hunter2"}},
+		{name: "kv v1 custom keys", config: VaultConfig{Address: kv1.URL, Path: "/kv/stork", UserKey: "user", PasswordKey: "pass"}, token: "good-token", want: &Credentials{User: "stork1", Password: "hunter1"}},
+		{name: "missing secret", config: VaultConfig{Address: kv2.URL, Path: "secret/data/other"}, token: "good-token"},
+		{name: "bad token", config: VaultConfig{Address: kv2.URL, Path: "secret/data/stork"}, token: "bad-token", err: "vault returned 403"},
+		{name: "missing keys", config: VaultConfig{Address: incomplete.URL, Path: "secret/data/stork"}, token: "good-token", err: `no "username"/"password" keys`},
+		{name: "not configured", config: VaultConfig{}, token: "good-token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("VAULT_ADDR", "")
+			t.Setenv("VAULT_TOKEN", test.token)
+
+			creds, err := vaultProvider{Config: test.config}.Credentials("Production", nil)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(creds, test.want) {
+				t.Errorf("got %+v, want %+v", creds, test.want)
+			}
+		})
+	}
+}
+
+func TestDefaultCredentialProviderOrder(t *testing.T) {
+	providers, err := credentialProviders(EnvironmentConfig{}, storkCredentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, provider := range providers {
+		names = append(names, provider.Name())
+	}
+	want := []string{"env", "helper", "vault", "keyring", "prompt"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	if _, err = credentialProviders(EnvironmentConfig{Credentials: []string{"env", "carrier-pigeon"}}, storkCredentials); err == nil {
+		t.Error("unknown provider accepted")
+	}
+}
+
+// stubProvider answers with fixed credentials or an error.
+type stubProvider struct {
+	name  string
+	creds *Credentials
+	err   error
+}
+
+func (s stubProvider) Name() string { return s.name }
+
+func (s stubProvider) Credentials(string, *url.URL) (*Credentials, error) {
+	return s.creds, s.err
+}
+
+func TestFirstCredentials(t *testing.T) {
+	env := stubProvider{name: "env", creds: &Credentials{User: "env"}}
+	helper := stubProvider{name: "helper", creds: &Credentials{User: "helper"}}
+	vault := stubProvider{name: "vault", creds: &Credentials{User: "vault"}}
+	keyring := stubProvider{name: "keyring", creds: &Credentials{User: "keyring"}}
+	prompt := stubProvider{name: "prompt", creds: &Credentials{User: "prompt"}}
+	empty := func(name string) stubProvider { return stubProvider{name: name} }
+
+	tests := []struct {
+		name      string
+		providers []CredentialProvider
+		want      string
+		err       string
+	}{
+		{name: "env first", providers: []CredentialProvider{env, helper, vault, keyring, prompt}, want: "env"},
+		{name: "then helper", providers: []CredentialProvider{empty("env"), helper, vault, keyring, prompt}, want: "helper"},
+		{name: "then vault", providers: []CredentialProvider{empty("env"), empty("helper"), vault, keyring, prompt}, want: "vault"},
+		{name: "then keyring", providers: []CredentialProvider{empty("env"), empty("helper"), empty("vault"), keyring, prompt}, want: "keyring"},
+		{name: "then prompt", providers: []CredentialProvider{empty("env"), empty("helper"), empty("vault"), empty("keyring"), prompt}, want: "prompt"},
+		{name: "none", providers: []CredentialProvider{empty("env"), empty("prompt")}},
+		{name: "error stops the chain", providers: []CredentialProvider{empty("env"), stubProvider{name: "vault", err: errors.New("sealed")}, keyring}, err: "vault credential provider: sealed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			creds, err := firstCredentials(test.providers, "Production", nil)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if creds != nil {
+				got = creds.User
+			}
+			if got != test.want {
+				t.Errorf("got credentials from %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCredentialKinds(t *testing.T) {
+	env := EnvironmentConfig{
+		Credentials: []string{"env", "keyring", "vault"},
+		Vault:       VaultConfig{Path: "secret/data/stork"},
+		KeaVault:    VaultConfig{Path: "secret/data/kea"},
+	}
+	tests := []struct {
+		kind    credentialKind
+		env     envProvider
+		keyring keyringProvider
+		vault   string
+	}{
+		{kind: storkCredentials, env: envProvider{UserVar: "STORK_USER", PasswordVar: "STORK_PASS"}, vault: "secret/data/stork"},
+		{kind: keaCredentials, env: envProvider{UserVar: "DHCLI_KEA_USER", PasswordVar: "DHCLI_KEA_PASSWORD"}, keyring: keyringProvider{AccountSuffix: "/kea"}, vault: "secret/data/kea"},
+	}
+	for _, test := range tests {
+		t.Run(test.kind.Label, func(t *testing.T) {
+			providers, err := credentialProviders(env, test.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if providers[0] != test.env {
+				t.Errorf("got %+v, want %+v", providers[0], test.env)
+			}
+			if providers[1] != test.keyring {
+				t.Errorf("got %+v, want %+v", providers[1], test.keyring)
+			}
+			if path := providers[2].(vaultProvider).Config.Path; path != test.vault {
+				t.Errorf("got vault path %s, want %s", path, test.vault)
+			}
+		})
+	}
+}
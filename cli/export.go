@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// exportPageSize is the number of leases requested per lease*-get-page call.
+const exportPageSize = 1000
+
+type LeasesExportCmd struct {
+	Selector string `kong:"arg='',name='instance-or-subnet',completion='instances',help='Kea instance (e.g. NYC3) or subnet (e.g. 10.30.2.0/24)'"`
+	Format   string `kong:"optional,short='f',default='kea-csv',enum='kea-csv,json',help='Output format: kea-csv (Kea memfile layout) or json.'"`
+	Daemon   string `kong:"optional,short='d',default='dhcp4',enum='dhcp4,dhcp6',help='Daemon whose leases to export when exporting an instance.'"`
+	Output   string `kong:"optional,short='o',help='Write to this file instead of stdout.'"`
+}
+
+// KeaLease is a lease as returned by Kea's lease commands. DHCPv4 leases
+// leave the DHCPv6 fields empty.
+type KeaLease struct {
+	IpAddress    string          `json:"ip-address"`
+	HwAddress    string          `json:"hw-address,omitempty"`
+	ClientID     string          `json:"client-id,omitempty"`
+	Duid         string          `json:"duid,omitempty"`
+	Iaid         int64           `json:"iaid,omitempty"`
+	Type         string          `json:"type,omitempty"`
+	PrefixLen    int             `json:"prefix-len,omitempty"`
+	PreferredLft int64           `json:"preferred-lft,omitempty"`
+	ValidLft     int64           `json:"valid-lft"`
+	Cltt         int64           `json:"cltt"`
+	SubnetID     int             `json:"subnet-id"`
+	FqdnFwd      bool            `json:"fqdn-fwd"`
+	FqdnRev      bool            `json:"fqdn-rev"`
+	Hostname     string          `json:"hostname"`
+	State        int             `json:"state"`
+	UserContext  json.RawMessage `json:"user-context,omitempty"`
+}
+
+// Column layouts of Kea's memfile lease files.
+var (
+	memfile4Header = []string{"address", "hwaddr", "client_id", "valid_lifetime", "expire", "subnet_id", "fqdn_fwd", "fqdn_rev", "hostname", "state", "user_context"}
+	memfile6Header = []string{"address", "duid", "valid_lifetime", "expire", "subnet_id", "pref_lifetime", "lease_type", "iaid", "prefix_len", "fqdn_fwd", "fqdn_rev", "hostname", "hwaddr", "state", "user_context", "hwtype", "hwaddr_source"}
+)
+
+// memfileLeaseTypes numbers DHCPv6 lease types as memfile does.
+var memfileLeaseTypes = map[string]string{"IA_NA": "0", "IA_TA": "1", "IA_PD": "2"}
+
+// memfileEscape escapes commas the way Kea does in free text columns.
+func memfileEscape(s string) string {
+	return strings.ReplaceAll(s, ",", "&#x2c")
+}
+
+func memfileBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// userContext renders a lease's user context as compact JSON, or "".
+func (l *KeaLease) userContext() string {
+	if len(l.UserContext) == 0 || string(l.UserContext) == "null" {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(l.UserContext, &v); err != nil {
+		return ""
+	}
+	out, _ := json.Marshal(v)
+	return memfileEscape(string(out))
+}
+
+// memfileRow returns the lease as a row of the memfile layout for its family.
+func (l *KeaLease) memfileRow(v6 bool) []string {
+	expire := strconv.FormatInt(l.Cltt+l.ValidLft, 10)
+	if !v6 {
+		return []string{
+			l.IpAddress,
+			l.HwAddress,
+			l.ClientID,
+			strconv.FormatInt(l.ValidLft, 10),
+			expire,
+			strconv.Itoa(l.SubnetID),
+			memfileBool(l.FqdnFwd),
+			memfileBool(l.FqdnRev),
+			memfileEscape(l.Hostname),
+			strconv.Itoa(l.State),
+			l.userContext(),
+		}
+	}
+
+	hwtype, hwsource := "", ""
+	if l.HwAddress != "" {
+		hwtype, hwsource = "1", "0"
+	}
+	return []string{
+		l.IpAddress,
+		l.Duid,
+		strconv.FormatInt(l.ValidLft, 10),
+		expire,
+		strconv.Itoa(l.SubnetID),
+		strconv.FormatInt(l.PreferredLft, 10),
+		memfileLeaseTypes[l.Type],
+		strconv.FormatInt(l.Iaid, 10),
+		strconv.Itoa(l.PrefixLen),
+		memfileBool(l.FqdnFwd),
+		memfileBool(l.FqdnRev),
+		memfileEscape(l.Hostname),
+		l.HwAddress,
+		strconv.Itoa(l.State),
+		l.userContext(),
+		hwtype,
+		hwsource,
+	}
+}
+
+// getLeasePages pages through every lease of a daemon, passing each page to
+// page.
+func getLeasePages(app *KeaAppDetail, daemon string, page func([]KeaLease) error) error {
+	command := "lease4-get-page"
+	if daemon == "dhcp6" {
+		command = "lease6-get-page"
+	}
+
+	from := "start"
+	for {
+		var out struct {
+			Leases []KeaLease `json:"leases"`
+			Count  int        `json:"count"`
+		}
+		args := map[string]interface{}{"from": from, "limit": exportPageSize}
+		err := keaCommand(app, daemon, command, args, &out)
+		if errors.Is(err, errKeaEmpty) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = page(out.Leases); err != nil {
+			return err
+		}
+		if len(out.Leases) < exportPageSize {
+			return nil
+		}
+		from = out.Leases[len(out.Leases)-1].IpAddress
+	}
+}
+
+// exportSource resolves what to export: the instance to ask, its daemon and
+// the Kea subnet ID to keep, 0 for all.
+func (e *LeasesExportCmd) exportSource() (*KeaAppDetail, string, int, error) {
+	if prefix, err := netip.ParsePrefix(e.Selector); err == nil {
+		envs, err := storkLogin()
+		if err != nil {
+			return nil, "", 0, err
+		}
+		match, err := findSubnet(prefix.Masked(), envs)
+		if err != nil {
+			return nil, "", 0, err
+		}
+		if len(match.Subnet.LocalSubnets) == 0 {
+			return nil, "", 0, fmt.Errorf("no Kea instance serves %s", match.Subnet.Subnet)
+		}
+
+		// HA partners hold the same leases, so the first instance will do.
+		local := match.Subnet.LocalSubnets[0]
+		app, err := getApp(local.AppName, match.Env.url, match.Env.jar)
+		if err != nil {
+			return nil, "", 0, err
+		}
+		daemon := "dhcp4"
+		if match.Prefix.Addr().Is6() {
+			daemon = "dhcp6"
+		}
+		return app, daemon, local.SubnetID, nil
+	}
+
+	envName, envURL, err := instanceEnvironment(e.Selector)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	jar, err := storkAuth(envURL)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("%s: %w", envName, err)
+	}
+	app, err := getApp(e.Selector, envURL, jar)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("%s: %s: %w", envName, e.Selector, err)
+	}
+	return app, e.Daemon, 0, nil
+}
+
+func (e *LeasesExportCmd) Run() error {
+	app, daemon, subnetID, err := e.exportSource()
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if e.Output != "" {
+		if file, err = os.Create(e.Output); err != nil {
+			return err
+		}
+		// Only cleans up after an early return; the file is closed below.
+		defer file.Close()
+		out = file
+	}
+	w := bufio.NewWriter(out)
+
+	count := 0
+	switch e.Format {
+	case "json":
+		all := []KeaLease{}
+		err = getLeasePages(app, daemon, func(leases []KeaLease) error {
+			for _, lease := range leases {
+				if subnetID == 0 || lease.SubnetID == subnetID {
+					all = append(all, lease)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		var data []byte
+		if data, err = json.MarshalIndent(all, "", "  "); err != nil {
+			return err
+		}
+		if _, err = w.Write(append(data, '\n')); err != nil {
+			return err
+		}
+		count = len(all)
+	default:
+		header := memfile4Header
+		if daemon == "dhcp6" {
+			header = memfile6Header
+		}
+		// Kea's memfile isn't RFC 4180 CSV: commas are escaped instead and
+		// nothing is quoted, so rows are written verbatim.
+		fmt.Fprintln(w, strings.Join(header, ","))
+		err = getLeasePages(app, daemon, func(leases []KeaLease) error {
+			for i := range leases {
+				if subnetID != 0 && leases[i].SubnetID != subnetID {
+					continue
+				}
+				fmt.Fprintln(w, strings.Join(leases[i].memfileRow(daemon == "dhcp6"), ","))
+				count++
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	// A failed final write may only show when the file is closed, e.g. on
+	// NFS or a full disk.
+	if file != nil {
+		if err = file.Close(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d leases from %s %s\n", count, app.Name, daemon)
+	return nil
+}
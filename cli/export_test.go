@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMemfileEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "host.example.com", want: "host.example.com"},
+		{in: "a,b,c", want: "a&#x2cb&#x2cc"},
+		{in: "", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			if got := memfileEscape(test.in); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMemfileRow(t *testing.T) {
+	tests := []struct {
+		name  string
+		lease string
+		v6    bool
+		want  string
+	}{
+		{
+			name:  "v4",
+			lease: `{"ip-address":"10.0.0.5","hw-address":"aa:bb:cc:dd:ee:ff","client-id":"01:aa:bb:cc:dd:ee:ff","valid-lft":3600,"cltt":1700000000,"subnet-id":12,"fqdn-fwd":true,"hostname":"printer,2","state":0}`,
+			want:  "10.0.0.5,aa:bb:cc:dd:ee:ff,01:aa:bb:cc:dd:ee:ff,3600,1700003600,12,1,0,printer&#x2c2,0,",
+		},
+		{
+			name:  "v4 user context",
+			lease: `{"ip-address":"10.0.0.6","valid-lft":60,"cltt":100,"subnet-id":1,"state":1,"user-context":{"b":1,"a":"x,y"}}`,
+			want:  `10.0.0.6,,,60,160,1,0,0,,1,{"a":"x&#x2cy"&#x2c"b":1}`,
+		},
+		{
+			name:  "v6 address",
+			lease: `{"ip-address":"2001:db8::5","duid":"00:01:02","iaid":7,"type":"IA_NA","preferred-lft":1800,"valid-lft":3600,"cltt":1000,"subnet-id":3,"fqdn-rev":true,"hostname":"h","hw-address":"aa:bb:cc:dd:ee:ff","state":0}`,
+			v6:    true,
+			want:  "2001:db8::5,00:01:02,3600,4600,3,1800,0,7,0,0,1,h,aa:bb:cc:dd:ee:ff,0,,1,0",
+		},
+		{
+			name:  "v6 prefix",
+			lease: `{"ip-address":"2001:db8:1::","duid":"00:01:02","iaid":8,"type":"IA_PD","prefix-len":56,"valid-lft":3600,"cltt":0,"subnet-id":3,"state":0,"user-context":null}`,
+			v6:    true,
+			want:  "2001:db8:1::,00:01:02,3600,3600,3,0,2,8,56,0,0,,,0,,,",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lease := KeaLease{}
+			if err := json.Unmarshal([]byte(test.lease), &lease); err != nil {
+				t.Fatal(err)
+			}
+			row := lease.memfileRow(test.v6)
+			header := memfile4Header
+			if test.v6 {
+				header = memfile6Header
+			}
+			if len(row) != len(header) {
+				t.Fatalf("got %d columns, want %d", len(row), len(header))
+			}
+			if got := strings.Join(row, ","); got != test.want {
+				t.Errorf("got  %s\nwant %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"math/big"
+	"net/netip"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type FreeCmd struct {
+	Subnet       string `kong:"arg='',name='cidr-or-subnet-id',help='CIDR within a subnet (e.g. 10.4.2.0/24) or Kea subnet ID'"`
+	Count        int    `kong:"optional,short='n',help='Only list the first N free addresses.'"`
+	OutsidePools bool   `kong:"optional,name='outside-pools',help='Only list addresses outside the dynamic pools, e.g. for static hosts.'"`
+}
+
+// addrRange is an inclusive range of addresses.
+type addrRange struct {
+	first netip.Addr
+	last  netip.Addr
+}
+
+// Size returns the number of addresses in the range.
+func (r addrRange) Size() *big.Int {
+	first := new(big.Int).SetBytes(r.first.AsSlice())
+	last := new(big.Int).SetBytes(r.last.AsSlice())
+	return last.Sub(last, first).Add(last, big.NewInt(1))
+}
+
+// addrAdd returns the address n after addr. The sum must not overflow.
+func addrAdd(addr netip.Addr, n *big.Int) netip.Addr {
+	sum := new(big.Int).SetBytes(addr.AsSlice())
+	sum.Add(sum, n)
+	out, _ := netip.AddrFromSlice(sum.FillBytes(make([]byte, addr.BitLen()/8)))
+	return out
+}
+
+func (r addrRange) String() string {
+	if r.first == r.last {
+		return r.first.String()
+	}
+	return r.first.String() + "-" + r.last.String()
+}
+
+// freeRanges returns the parts of within not covered by any of taken.
+func freeRanges(within addrRange, taken []addrRange) []addrRange {
+	if within.last.Less(within.first) {
+		return nil
+	}
+	sort.Slice(taken, func(i, j int) bool { return taken[i].first.Less(taken[j].first) })
+
+	var free []addrRange
+	next := within.first
+	for _, t := range taken {
+		if t.last.Less(next) {
+			continue
+		}
+		if within.last.Less(t.first) {
+			break
+		}
+		if next.Less(t.first) {
+			free = append(free, addrRange{next, t.first.Prev()})
+		}
+		if !t.last.Less(within.last) {
+			return free
+		}
+		next = t.last.Next()
+	}
+	return append(free, addrRange{next, within.last})
+}
+
+// limitRanges keeps the first count addresses of ranges.
+func limitRanges(ranges []addrRange, count int) []addrRange {
+	var out []addrRange
+	left := big.NewInt(int64(count))
+	for _, r := range ranges {
+		if left.Sign() <= 0 {
+			break
+		}
+		if r.Size().Cmp(left) <= 0 {
+			out = append(out, r)
+			left.Sub(left, r.Size())
+			continue
+		}
+		out = append(out, addrRange{r.first, addrAdd(r.first, left.Sub(left, big.NewInt(1)))})
+		break
+	}
+	return out
+}
+
+// subnetLeases returns the addresses leased or declined in a subnet, as seen
+// by the instances serving it.
+func subnetLeases(match *SubnetMatch) (map[netip.Addr]bool, error) {
+	service, command := "dhcp4", "lease4-get-all"
+	if match.Prefix.Addr().Is6() {
+		service, command = "dhcp6", "lease6-get-all"
+	}
+
+	leased := map[netip.Addr]bool{}
+	answered := 0
+	var lastErr error
+	for _, local := range match.Subnet.LocalSubnets {
+		app, err := getApp(local.AppName, match.Env.url, match.Env.jar)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		var out struct {
+			Leases []struct {
+				IpAddress string `json:"ip-address"`
+				State     int    `json:"state"`
+			} `json:"leases"`
+		}
+		err = keaCommand(app, service, command, map[string][]int{"subnets": {local.SubnetID}}, &out)
+		if err != nil && !errors.Is(err, errKeaEmpty) {
+			fmt.Printf("%s: %s: %s\n", match.Env.name, local.AppName, err.Error())
+			lastErr = err
+			continue
+		}
+		answered++
+
+		for _, lease := range out.Leases {
+			addr, err := netip.ParseAddr(lease.IpAddress)
+			if err == nil && lease.State != leaseReclaimed {
+				leased[addr] = true
+			}
+		}
+	}
+
+	// HA partners hold the same leases, so one answer is enough.
+	if answered == 0 {
+		return nil, fmt.Errorf("could not read leases: %w", lastErr)
+	}
+	return leased, nil
+}
+
+// subnetReservations returns the reserved addresses of a subnet.
+func subnetReservations(match *SubnetMatch) (map[netip.Addr]bool, error) {
+	hosts, err := retryStale(match.Env.url, func() ([]KeaHost, error) {
+		id, err := subnetID(match.Prefix, match.Env)
+		if err != nil {
+			return nil, err
+		}
+		query := url.Values{}
+		query.Set("subnetId", strconv.Itoa(id))
+		return getAllPages[KeaHost]("/api/hosts", query, match.Env.url, match.Env.jar)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing reservations: %w", err)
+	}
+
+	reserved := map[netip.Addr]bool{}
+	for _, host := range hosts {
+		for _, reservation := range host.AddressReservations {
+			address, _, _ := strings.Cut(reservation.Address, "/")
+			if addr, err := netip.ParseAddr(address); err == nil {
+				reserved[addr] = true
+			}
+		}
+	}
+	return reserved, nil
+}
+
+// findSubnetByID finds a subnet by the ID Kea knows it by. Kea instances
+// number their subnets independently, so the ID must be unambiguous.
+func findSubnetByID(id int, envs []stork) (*SubnetMatch, error) {
+	var matches []SubnetMatch
+	for _, env := range envs {
+		subnets, err := getSubnets(env.url, env.jar)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", env.name, err)
+		}
+		for _, item := range subnets.Items {
+			for _, local := range item.LocalSubnets {
+				if local.SubnetID != id {
+					continue
+				}
+				prefix, err := netip.ParsePrefix(item.Subnet)
+				if err != nil {
+					continue
+				}
+				matches = append(matches, SubnetMatch{Env: env, Subnet: item, Prefix: prefix.Masked()})
+				break
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no subnet with ID %d", id)
+	case 1:
+		return &matches[0], nil
+	}
+	var prefixes []string
+	for _, match := range matches {
+		prefixes = append(prefixes, fmt.Sprintf("%s (%s)", match.Subnet.Subnet, match.Env.name))
+	}
+	return nil, fmt.Errorf("subnet ID %d is ambiguous, use the CIDR: %s", id, strings.Join(prefixes, ", "))
+}
+
+func (f *FreeCmd) Run() error {
+	envs, err := storkLogin()
+	if err != nil {
+		return err
+	}
+
+	var match *SubnetMatch
+	within := netip.Prefix{}
+	if id, err := strconv.Atoi(f.Subnet); err == nil {
+		match, err = findSubnetByID(id, envs)
+		if err != nil {
+			return err
+		}
+		within = match.Prefix
+	} else {
+		within, err = parseTarget(f.Subnet)
+		if err != nil {
+			return err
+		}
+		match, err = findSubnet(within, envs)
+		if err != nil {
+			return err
+		}
+	}
+
+	first, last, err := prefixRange(within)
+	if err != nil {
+		return err
+	}
+	// The network and broadcast addresses of an IPv4 subnet can't be used.
+	if first.Is4() && match.Prefix.Bits() < 31 {
+		if first == match.Prefix.Addr() {
+			first = first.Next()
+		}
+		if _, broadcast, err := prefixRange(match.Prefix); err == nil && last == broadcast {
+			last = last.Prev()
+		}
+	}
+
+	reserved, err := subnetReservations(match)
+	if err != nil {
+		return err
+	}
+	leased, err := subnetLeases(match)
+	if err != nil {
+		return err
+	}
+
+	var taken []addrRange
+	for addr := range reserved {
+		taken = append(taken, addrRange{addr, addr})
+	}
+	for addr := range leased {
+		taken = append(taken, addrRange{addr, addr})
+	}
+	if f.OutsidePools {
+		for _, pool := range match.Subnet.AllPools() {
+			if start, end, err := poolRange(pool); err == nil {
+				taken = append(taken, addrRange{start, end})
+			}
+		}
+	}
+
+	free := freeRanges(addrRange{first, last}, taken)
+	total := new(big.Int)
+	for _, r := range free {
+		total.Add(total, r.Size())
+	}
+	if f.Count > 0 {
+		free = limitRanges(free, f.Count)
+	}
+
+	where := "including dynamic pools"
+	if f.OutsidePools {
+		where = "outside dynamic pools"
+	}
+	fmt.Printf("\n%s: %s in subnet %s: %s free addresses %s (%d reserved, %d leased)\n",
+		match.Env.name, within, match.Subnet.Subnet, total, where, len(reserved), len(leased))
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetBorder(false)
+	table.SetHeader([]string{"Free Addresses", "Count"})
+	for _, r := range free {
+		table.Append([]string{r.String(), r.Size().String()})
+	}
+	table.Render()
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"math/big"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRange parses "first-last" or a single address.
+func testRange(t *testing.T, text string) addrRange {
+	first, last, found := strings.Cut(text, "-")
+	if !found {
+		last = first
+	}
+	return addrRange{netip.MustParseAddr(first), netip.MustParseAddr(last)}
+}
+
+func testRanges(t *testing.T, texts []string) []addrRange {
+	var ranges []addrRange
+	for _, text := range texts {
+		ranges = append(ranges, testRange(t, text))
+	}
+	return ranges
+}
+
+func rangeStrings(ranges []addrRange) []string {
+	var out []string
+	for _, r := range ranges {
+		out = append(out, r.String())
+	}
+	return out
+}
+
+func TestAddrRangeSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "10.0.0.1", want: "1"},
+		{in: "10.0.0.0-10.0.0.1", want: "2"},
+		{in: "255.255.255.0-255.255.255.255", want: "256"},
+		{in: "0.0.0.0-255.255.255.255", want: "4294967296"},
+		{in: "2001:db8::-2001:db8::ffff:ffff:ffff:ffff", want: "18446744073709551616"},
+		{in: "::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", want: "340282366920938463463374607431768211456"},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			if got := testRange(t, test.in).Size().String(); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFreeRanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		within string
+		taken  []string
+		want   []string
+		size   string
+	}{
+		{
+			name:   "nothing taken",
+			within: "10.0.0.1-10.0.0.254",
+			want:   []string{"10.0.0.1-10.0.0.254"},
+		},
+		{
+			name:   "pool at 255.255.255.255",
+			within: "255.255.255.0-255.255.255.255",
+			taken:  []string{"255.255.255.250-255.255.255.255"},
+			want:   []string{"255.255.255.0-255.255.255.249"},
+		},
+		{
+			name:   "free up to 255.255.255.255",
+			within: "255.255.255.0-255.255.255.255",
+			taken:  []string{"255.255.255.0-255.255.255.9"},
+			want:   []string{"255.255.255.10-255.255.255.255"},
+		},
+		{
+			name:   "/31 with one taken",
+			within: "10.0.0.0-10.0.0.1",
+			taken:  []string{"10.0.0.0"},
+			want:   []string{"10.0.0.1"},
+		},
+		{
+			name:   "/32 free",
+			within: "10.0.0.7",
+			want:   []string{"10.0.0.7"},
+		},
+		{
+			name:   "/32 taken",
+			within: "10.0.0.7",
+			taken:  []string{"10.0.0.7"},
+		},
+		{
+			name:   "fully taken",
+			within: "10.0.0.1-10.0.0.254",
+			taken:  []string{"10.0.0.1-10.0.0.100", "10.0.0.101-10.0.0.254"},
+		},
+		{
+			name:   "taken beyond both ends",
+			within: "10.0.0.1-10.0.0.254",
+			taken:  []string{"10.0.0.0-10.0.1.255"},
+		},
+		{
+			name:   "adjacent taken",
+			within: "10.0.0.1-10.0.0.254",
+			taken:  []string{"10.0.0.10-10.0.0.12", "10.0.0.5-10.0.0.9"},
+			want:   []string{"10.0.0.1-10.0.0.4", "10.0.0.13-10.0.0.254"},
+		},
+		{
+			name:   "overlapping and duplicate taken",
+			within: "10.0.0.1-10.0.0.254",
+			taken:  []string{"10.0.0.5-10.0.0.20", "10.0.0.10-10.0.0.12", "10.0.0.15-10.0.0.30", "10.0.0.40", "10.0.0.40"},
+			want:   []string{"10.0.0.1-10.0.0.4", "10.0.0.31-10.0.0.39", "10.0.0.41-10.0.0.254"},
+		},
+		{
+			name:   "taken outside",
+			within: "10.0.0.1-10.0.0.254",
+			taken:  []string{"10.0.1.5", "9.255.255.255"},
+			want:   []string{"10.0.0.1-10.0.0.254"},
+		},
+		{
+			name:   "IPv6 range larger than 2^64",
+			within: "2001:db8::-2001:db8:0:ffff:ffff:ffff:ffff:ffff",
+			taken:  []string{"2001:db8::5", "2001:db8::1:0-2001:db8::1:ffff"},
+			want:   []string{"2001:db8::-2001:db8::4", "2001:db8::6-2001:db8::ffff", "2001:db8::2:0-2001:db8:0:ffff:ffff:ffff:ffff:ffff"},
+			size:   "1208925819614629174640639",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			free := freeRanges(testRange(t, test.within), testRanges(t, test.taken))
+			if got := rangeStrings(free); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			if test.size != "" {
+				total := new(big.Int)
+				for _, r := range free {
+					total.Add(total, r.Size())
+				}
+				if total.String() != test.size {
+					t.Errorf("got %s free, want %s", total, test.size)
+				}
+			}
+		})
+	}
+}
+
+func TestLimitRanges(t *testing.T) {
+	ranges := []string{"10.0.0.1-10.0.0.3", "10.0.0.10-10.0.0.20"}
+	tests := []struct {
+		name   string
+		ranges []string
+		count  int
+		want   []string
+	}{
+		{name: "inside the first range", ranges: ranges, count: 1, want: []string{"10.0.0.1"}},
+		{name: "first range exactly", ranges: ranges, count: 3, want: []string{"10.0.0.1-10.0.0.3"}},
+		{name: "split the second range", ranges: ranges, count: 5, want: []string{"10.0.0.1-10.0.0.3", "10.0.0.10-10.0.0.11"}},
+		{name: "all ranges", ranges: ranges, count: 14, want: []string{"10.0.0.1-10.0.0.3", "10.0.0.10-10.0.0.20"}},
+		{name: "more than available", ranges: ranges, count: 100, want: []string{"10.0.0.1-10.0.0.3", "10.0.0.10-10.0.0.20"}},
+		{name: "up to 255.255.255.255", ranges: []string{"255.255.255.250-255.255.255.255"}, count: 6, want: []string{"255.255.255.250-255.255.255.255"}},
+		{name: "large IPv6 range", ranges: []string{"2001:db8::-2001:db8:0:ffff:ffff:ffff:ffff:ffff"}, count: 70000, want: []string{"2001:db8::-2001:db8::1:116f"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := rangeStrings(limitRanges(testRanges(t, test.ranges), test.count)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
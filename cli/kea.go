@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Result codes of Kea control commands.
+const (
+	keaSuccess = 0
+	keaEmpty   = 3
+)
+
+// errKeaEmpty is returned when a Kea command found nothing, e.g. no such lease.
+var errKeaEmpty = errors.New("not found")
+
+// KeaResponse is the answer of one Kea daemon to a control command.
+type KeaResponse struct {
+	Result    int             `json:"result"`
+	Text      string          `json:"text"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type keaRequest struct {
+	Command   string      `json:"command"`
+	Service   []string    `json:"service,omitempty"`
+	Arguments interface{} `json:"arguments,omitempty"`
+}
+
+var (
+	// keaAuth holds the control agent credentials of each environment once
+	// looked up, nil if no provider had any, so the chain runs once a run.
+	keaAuth   = map[string]*Credentials{}
+	keaAuthMu sync.Mutex
+)
+
+// controlAgentCredentials returns the basic auth credentials for the control
+// agents of an environment, walking the credential provider chain the first
+// time they are asked for.
+func controlAgentCredentials(envName string, agentURL *url.URL) (*Credentials, error) {
+	keaAuthMu.Lock()
+	defer keaAuthMu.Unlock()
+	if creds, ok := keaAuth[envName]; ok {
+		return creds, nil
+	}
+	creds, err := lookupKind(keaCredentials, envName, agentURL)
+	if err != nil {
+		return nil, err
+	}
+	keaAuth[envName] = creds
+	return creds, nil
+}
+
+// postKea posts a command to a control agent, with basic auth if creds are
+// given.
+func postKea(address string, body []byte, creds *Credentials) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if creds != nil {
+		req.SetBasicAuth(creds.User, creds.Password)
+	}
+	return storkClient(nil).Do(req)
+}
+
+// keaCommand sends a command to a daemon through the control agent of a Kea
+// app and decodes the response arguments into out, if given. When the control
+// agent asks for basic auth, the credentials are looked up through the
+// credential provider chain (see keaCredentials) and the command is sent again.
+func keaCommand(app *KeaAppDetail, service string, command string, arguments interface{}, out interface{}) error {
+	address := app.ControlAddress()
+	if address == "" {
+		return fmt.Errorf("%s has no control agent", app.Name)
+	}
+
+	body, err := json.Marshal(keaRequest{Command: command, Service: []string{service}, Arguments: arguments})
+	if err != nil {
+		return err
+	}
+
+	keaAuthMu.Lock()
+	creds := keaAuth[app.envName]
+	keaAuthMu.Unlock()
+
+	resp, err := postKea(address, body, creds)
+	if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+
+	// Credentials are only looked up once an agent asks for them, so agents
+	// without basic auth never cause a prompt.
+	if resp.StatusCode == http.StatusUnauthorized && creds == nil {
+		resp.Body.Close()
+		agentURL, err := url.Parse(address)
+		if err != nil {
+			return err
+		}
+		creds, err = controlAgentCredentials(app.envName, agentURL)
+		if err != nil {
+			return fmt.Errorf("%s: %w", command, err)
+		}
+		if creds == nil {
+			return fmt.Errorf("%s: control agent of %s requires credentials: set DHCLI_KEA_USER and DHCLI_KEA_PASSWORD or configure a credential provider (see README)", command, app.Name)
+		}
+		resp, err = postKea(address, body, creds)
+		if err != nil {
+			return fmt.Errorf("%s: %w", command, err)
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("%s: control agent of %s rejected the %s credentials", command, app.Name, app.envName)
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("%s: control agent of %s returned %d", command, app.Name, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// The control agent answers with one response per service.
+	var responses []KeaResponse
+	err = json.Unmarshal(data, &responses)
+	if err != nil {
+		return fmt.Errorf("%s: could not parse response: %w", command, err)
+	}
+	if len(responses) == 0 {
+		return fmt.Errorf("%s: empty response", command)
+	}
+
+	r := responses[0]
+	switch r.Result {
+	case keaSuccess:
+	case keaEmpty:
+		return fmt.Errorf("%s: %w", command, errKeaEmpty)
+	default:
+		return fmt.Errorf("%s failed on %s %s: %s", command, app.Name, service, r.Text)
+	}
+
+	if out == nil || len(r.Arguments) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(r.Arguments))
+	decoder.UseNumber()
+	return decoder.Decode(out)
+}
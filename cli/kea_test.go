@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeControlAgent answers statistic-get-all, asking for basic auth if user
+// is set.
+func fakeControlAgent(t *testing.T, user string, password string) (*KeaAppDetail, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if u, p, _ := r.BasicAuth(); user != "" && (u != user || p != password) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[{"result":0,"arguments":{"pkt4-received":[[10,"2024-05-01 10:00:00.000000"]]}}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	agent, _ := url.Parse(server.URL)
+	app := &KeaAppDetail{}
+	err := json.Unmarshal([]byte(fmt.Sprintf(`{"name":"NYC3","accessPoints":[{"type":"control","address":%q,"port":%s}]}`,
+		agent.Hostname(), agent.Port())), app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.envName = "Production"
+	return app, &requests
+}
+
+func TestKeaCommandCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		agent    [2]string
+		env      map[string]string
+		requests int
+		err      string
+	}{
+		{name: "no auth", requests: 1},
+		{name: "env credentials", agent: [2]string{"kea", "secret"}, env: map[string]string{"DHCLI_KEA_USER": "kea", "DHCLI_KEA_PASSWORD": "secret"}, requests: 2},
+		{name: "stork credentials not used", agent: [2]string{"kea", "secret"}, env: map[string]string{"STORK_USER": "kea", "STORK_PASS": "secret"}, requests: 1, err: "requires credentials"},
+		{name: "wrong credentials", agent: [2]string{"kea", "secret"}, env: map[string]string{"DHCLI_KEA_USER": "kea", "DHCLI_KEA_PASSWORD": "guess"}, requests: 2, err: "rejected the Production credentials"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Only the env provider, so the test never reaches a keyring or prompt.
+			config := filepath.Join(t.TempDir(), "config.json")
+			err := os.WriteFile(config, []byte(`{"environments":{"Production":{"credentials":["env"]}}}`), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("DHCLI_CONFIG", config)
+			for _, name := range []string{"DHCLI_KEA_USER", "DHCLI_KEA_PASSWORD", "STORK_USER", "STORK_PASS"} {
+				t.Setenv(name, "")
+				os.Unsetenv(name)
+			}
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			keaAuth = map[string]*Credentials{}
+
+			app, requests := fakeControlAgent(t, test.agent[0], test.agent[1])
+			stats, err := getStatistics(app, "dhcp4")
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if value, _ := stats.Value("pkt4-received"); value != 10 {
+				t.Errorf("got pkt4-received %v, want 10", value)
+			}
+			if *requests != test.requests {
+				t.Errorf("got %d requests, want %d", *requests, test.requests)
+			}
+
+			// Found credentials are sent up front from then on.
+			if test.err == "" && test.agent[0] != "" {
+				if _, err = getStatistics(app, "dhcp4"); err != nil || *requests != test.requests+1 {
+					t.Errorf("second command: %v after %d requests", err, *requests)
+				}
+			}
+		})
+	}
+}
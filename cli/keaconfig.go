@@ -0,0 +1,352 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigCmd groups the commands working on Kea daemon configuration.
+type ConfigCmd struct {
+	Show     ConfigShowCmd     `kong:"cmd='',help='Show the running configuration of a Kea daemon'"`
+	Diff     ConfigDiffCmd     `kong:"cmd='',help='Compare the configuration of two Kea instances'"`
+	Snapshot ConfigSnapshotCmd `kong:"cmd='',help='Save Kea daemon configurations as a baseline'"`
+	Drift    ConfigDriftCmd    `kong:"cmd='',help='Compare live Kea daemon configurations with a saved baseline'"`
+}
+
+type ConfigShowCmd struct {
+	Instance    string `kong:"arg='',name='kea-instance',completion='instances',help='e.g. NYC3, S2R8'"`
+	Daemon      string `kong:"optional,short='d',default='dhcp4',enum='dhcp4,dhcp6,d2,ca',help='Daemon whose configuration to show: dhcp4, dhcp6, d2 or ca.'"`
+	Path        string `kong:"optional,short='p',help='Only show part of the configuration, e.g. Dhcp4.subnet4[id=12].pools'"`
+	Format      string `kong:"optional,short='o',default='json',enum='json,yaml',help='Output format: json or yaml.'"`
+	ShowSecrets bool   `kong:"optional,name='show-secrets',help='Do not scrub passwords and other secrets.'"`
+}
+
+// KeaDaemonConfig is Stork's view of a daemon's running configuration.
+type KeaDaemonConfig struct {
+	AppID      int         `json:"appId"`
+	AppName    string      `json:"appName"`
+	DaemonName string      `json:"daemonName"`
+	Config     interface{} `json:"config"`
+}
+
+// Keys whose values are replaced by scrubConfig.
+var secretKey = regexp.MustCompile(`(?i)password|secret|token`)
+
+const scrubbed = "*****"
+
+// instanceEnvironment returns the environment a Kea instance lives in,
+// following its naming scheme.
+func instanceEnvironment(instance string) (string, *url.URL, error) {
+	envName := "Production"
+	if isStage2(instance) {
+		envName = "Stage2"
+	}
+	envURL, err := url.Parse(environments[envName])
+	if err != nil {
+		return "", nil, fmt.Errorf("error parsing environment URLs: %w", err)
+	}
+	return envName, envURL, nil
+}
+
+// getApp fetches the details of a Kea app by name. A 404 for a cached app ID
+// refreshes the metadata cache and retries.
+func getApp(appname string, envURL *url.URL, jar *cookiejar.Jar) (*KeaAppDetail, error) {
+	appID, err := getAppID(appname, envURL, jar)
+	if err != nil {
+		return nil, err
+	}
+
+	app := KeaAppDetail{}
+	err = storkGet(fmt.Sprintf("/api/apps/%d", appID), url.Values{}, envURL, jar, &app)
+	if errors.Is(err, errNotFound) && !refreshMetadata {
+		invalidateMetadata(environmentName(envURL))
+		refreshMetadata = true
+		return getApp(appname, envURL, jar)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error looking up app details: %w", err)
+	}
+	app.envName = environmentName(envURL)
+	return &app, nil
+}
+
+// getDaemonConfig fetches the running configuration of one daemon of a Kea
+// instance.
+func getDaemonConfig(instance string, daemonName string, envURL *url.URL, jar *cookiejar.Jar) (*KeaDaemonConfig, error) {
+	app, err := getApp(instance, envURL, jar)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, daemon := range app.Details.Daemons {
+		if daemon.Name == daemonName {
+			return getDaemonConfigByID(daemon.ID, envURL, jar)
+		}
+	}
+	return nil, fmt.Errorf("no %s daemon found on %s", daemonName, instance)
+}
+
+// getDaemonConfigByID fetches the running configuration of a daemon by its
+// Stork ID.
+func getDaemonConfigByID(daemonID int, envURL *url.URL, jar *cookiejar.Jar) (*KeaDaemonConfig, error) {
+	var raw json.RawMessage
+	err := storkGet(fmt.Sprintf("/api/daemons/%d/config", daemonID), url.Values{}, envURL, jar, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching daemon configuration: %w", err)
+	}
+
+	// Keep numbers as written so large integers and floats survive.
+	c := KeaDaemonConfig{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	err = decoder.Decode(&c)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing daemon configuration: %w", err)
+	}
+	if c.Config == nil {
+		return nil, fmt.Errorf("stork has no configuration for %s on %s", c.DaemonName, c.AppName)
+	}
+	return &c, nil
+}
+
+// scrubConfig returns a copy of a configuration with the values of
+// password-like keys replaced.
+func scrubConfig(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			if _, isString := child.(string); isString && secretKey.MatchString(key) {
+				out[key] = scrubbed
+				continue
+			}
+			out[key] = scrubConfig(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, child := range value {
+			out[i] = scrubConfig(child)
+		}
+		return out
+	}
+	return v
+}
+
+// pathSegment matches one step of a --path selector: a key followed by any
+// number of [index] or [field=value] filters.
+var pathSegment = regexp.MustCompile(`^([^.\[\]]+)((?:\[[^\]]*\])*)$`)
+var pathFilter = regexp.MustCompile(`\[([^\]]*)\]`)
+
+// selectPath walks a dotted path such as Dhcp4.subnet4[id=12].pools through
+// a configuration.
+func selectPath(v interface{}, path string) (interface{}, error) {
+	if path == "" {
+		return v, nil
+	}
+
+	for _, segment := range splitPath(path) {
+		m := pathSegment.FindStringSubmatch(segment)
+		if m == nil {
+			return nil, fmt.Errorf("invalid path segment %q", segment)
+		}
+
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: not an object", m[1])
+		}
+		v, ok = object[m[1]]
+		if !ok {
+			return nil, fmt.Errorf("%s: no such key", m[1])
+		}
+
+		for _, filter := range pathFilter.FindAllStringSubmatch(m[2], -1) {
+			list, ok := v.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s[%s]: not a list", m[1], filter[1])
+			}
+			v, ok = filterList(list, filter[1])
+			if !ok {
+				return nil, fmt.Errorf("%s[%s]: no match", m[1], filter[1])
+			}
+		}
+	}
+	return v, nil
+}
+
+// splitPath splits a --path selector at the dots between keys, leaving those
+// inside a [field=value] filter, e.g. [subnet=10.0.0.0/24], alone.
+func splitPath(path string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '[':
+			depth++
+		case ']':
+			if depth > 0 {
+				depth--
+			}
+		case '.':
+			if depth == 0 {
+				segments = append(segments, path[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, path[start:])
+}
+
+// filterList picks a list element by index or by a field=value match.
+func filterList(list []interface{}, filter string) (interface{}, bool) {
+	field, want, isMatch := strings.Cut(filter, "=")
+	if !isMatch {
+		index, err := strconv.Atoi(filter)
+		if err != nil || index < 0 || index >= len(list) {
+			return nil, false
+		}
+		return list[index], true
+	}
+
+	for _, item := range list {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if value, ok := object[field]; ok && fmt.Sprint(value) == want {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+// formatConfig renders a configuration as indented JSON or YAML, with keys
+// in sorted order.
+func formatConfig(v interface{}, format string) (string, error) {
+	if format == "yaml" {
+		var b strings.Builder
+		writeYAML(&b, v, 0)
+		return b.String(), nil
+	}
+
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
+}
+
+var yamlPlainKey = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_-]*$`)
+
+func yamlKey(key string) string {
+	if yamlPlainKey.MatchString(key) {
+		return key
+	}
+	quoted, _ := json.Marshal(key)
+	return string(quoted)
+}
+
+// yamlScalar renders a JSON scalar, or an empty collection, as YAML. Strings
+// use JSON quoting, which is valid YAML.
+func yamlScalar(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case nil:
+		return "null", true
+	case map[string]interface{}:
+		if len(value) == 0 {
+			return "{}", true
+		}
+		return "", false
+	case []interface{}:
+		if len(value) == 0 {
+			return "[]", true
+		}
+		return "", false
+	case string:
+		quoted, _ := json.Marshal(value)
+		return string(quoted), true
+	}
+	return fmt.Sprint(v), true
+}
+
+func writeYAML(b *strings.Builder, v interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+
+	if scalar, ok := yamlScalar(v); ok {
+		b.WriteString(pad + scalar + "\n")
+		return
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if scalar, ok := yamlScalar(value[key]); ok {
+				b.WriteString(pad + yamlKey(key) + ": " + scalar + "\n")
+				continue
+			}
+			b.WriteString(pad + yamlKey(key) + ":\n")
+			writeYAML(b, value[key], indent+1)
+		}
+	case []interface{}:
+		for _, item := range value {
+			if scalar, ok := yamlScalar(item); ok {
+				b.WriteString(pad + "- " + scalar + "\n")
+				continue
+			}
+			// Render the item one level deeper, then hang its first line
+			// off the dash.
+			var nested strings.Builder
+			writeYAML(&nested, item, indent+1)
+			b.WriteString(pad + "- " + strings.TrimPrefix(nested.String(), pad+"  "))
+		}
+	}
+}
+
+func (c *ConfigShowCmd) Run() error {
+	envName, envURL, err := instanceEnvironment(c.Instance)
+	if err != nil {
+		return err
+	}
+
+	jar, err := storkAuth(envURL)
+	if err != nil {
+		return err
+	}
+
+	config, err := getDaemonConfig(c.Instance, c.Daemon, envURL, jar)
+	if err != nil {
+		fmt.Printf("%s: %s: %s\n", envName, c.Instance, err.Error())
+		return err
+	}
+
+	v := config.Config
+	if !c.ShowSecrets {
+		v = scrubConfig(v)
+	}
+
+	v, err = selectPath(v, c.Path)
+	if err != nil {
+		return fmt.Errorf("--path %s: %w", c.Path, err)
+	}
+
+	out, err := formatConfig(v, c.Format)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.WriteString(out)
+	return err
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// testConfig decodes a configuration the way getDaemonConfigByID does.
+func testConfig(t *testing.T, text string) interface{} {
+	var v interface{}
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestScrubConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{name: "password", config: `{"user":"kea","password":"hunter2"}`, want: `{"password":"` + scrubbed + `","user":"kea"}`},
+		{name: "nested in list", config: `{"hooks":[{"parameters":{"secret":"abc","port":1}}]}`, want: `{"hooks":[{"parameters":{"port":1,"secret":"` + scrubbed + `"}}]}`},
+		{name: "non-string kept", config: `{"password-min-length":12}`, want: `{"password-min-length":12}`},
+		{name: "nothing secret", config: `{"name":"password"}`, want: `{"name":"password"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original := testConfig(t, test.config)
+			if got := mustJSON(scrubConfig(original)); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+			if mustJSON(original) != mustJSON(testConfig(t, test.config)) {
+				t.Error("scrubConfig changed its input")
+			}
+		})
+	}
+}
+
+func TestSelectPath(t *testing.T) {
+	config := testConfig(t, `{"Dhcp4":{
+		"subnet4":[
+			{"id":12,"subnet":"10.0.0.0/24","pools":[{"pool":"10.0.0.10-10.0.0.20"}]},
+			{"id":13,"subnet":"10.0.1.0/24"}
+		],
+		"valid-lifetime":4000
+	}}`)
+
+	tests := []struct {
+		path string
+		want string
+		err  string
+	}{
+		{path: "", want: mustJSON(config)},
+		{path: "Dhcp4.valid-lifetime", want: `4000`},
+		{path: "Dhcp4.subnet4[1].subnet", want: `"10.0.1.0/24"`},
+		{path: "Dhcp4.subnet4[id=12].pools[0]", want: `{"pool":"10.0.0.10-10.0.0.20"}`},
+		{path: "Dhcp4.subnet4[subnet=10.0.1.0/24].id", want: `13`},
+		{path: "Dhcp4.subnet4[id=12].pools[pool=10.0.0.10-10.0.0.20].pool", want: `"10.0.0.10-10.0.0.20"`},
+		{path: "Dhcp4.subnet4[id=12][0]", err: "subnet4[0]: not a list"},
+		{path: "Dhcp4.subnet4[id=99]", err: "subnet4[id=99]: no match"},
+		{path: "Dhcp4.subnet4[2]", err: "subnet4[2]: no match"},
+		{path: "Dhcp4.valid-lifetime.x", err: "x: not an object"},
+		{path: "Dhcp4.option-data", err: "option-data: no such key"},
+		{path: "Dhcp4..subnet4", err: `invalid path segment ""`},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			got, err := selectPath(config, test.path)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mustJSON(got) != test.want {
+				t.Errorf("got %s, want %s", mustJSON(got), test.want)
+			}
+		})
+	}
+}
+
+func mustJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
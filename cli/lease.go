@@ -0,0 +1,291 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LeaseCmd groups the commands changing a single lease.
+type LeaseCmd struct {
+	Rm LeaseRmCmd `kong:"cmd='',help='Delete a lease'"`
+}
+
+type LeaseRmCmd struct {
+	Lease    string `kong:"arg='',name='ip-or-mac',help='e.g. 10.30.2.4 or 78:12:b6:d9:ce:58'"`
+	Instance string `kong:"optional,short='i',completion='instances',help='Delete the lease held on this Kea instance (and its HA partner) when several match.'"`
+	Yes      bool   `kong:"optional,short='y',help='Do not ask for confirmation.'"`
+}
+
+// leaseCopy is a lease as held by one Kea instance.
+type leaseCopy struct {
+	env   stork
+	lease LeaseItem
+}
+
+// findLeaseCopies returns every instance's copy of the leases matching an IP
+// or MAC address. HA partners each hold a copy, and unrelated instances may
+// lease the same address.
+func findLeaseCopies(term string, envs []stork) ([]leaseCopy, error) {
+	ip := net.ParseIP(term)
+	var copies []leaseCopy
+	for _, env := range envs {
+		l, err := getLeases(term, env.url, env.jar)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", env.name, err)
+		}
+		for _, lease := range l.Items {
+			if lease.State == leaseReclaimed {
+				continue
+			}
+			if ip != nil && !ip.Equal(net.ParseIP(lease.IpAddress)) {
+				continue
+			}
+			if ip == nil && !strings.EqualFold(lease.HwAddress, term) {
+				continue
+			}
+			copies = append(copies, leaseCopy{env: env, lease: lease})
+		}
+	}
+	return copies, nil
+}
+
+// haPeer is a server of a Kea high-availability relationship.
+type haPeer struct {
+	Name string
+	URL  string
+}
+
+// haPeers returns the HA peers configured in a DHCP daemon's configuration,
+// other than the server itself.
+func haPeers(config interface{}) []haPeer {
+	var peers []haPeer
+	top, _ := config.(map[string]interface{})
+	for _, daemon := range top {
+		daemon, _ := daemon.(map[string]interface{})
+		libraries, _ := daemon["hooks-libraries"].([]interface{})
+		for _, library := range libraries {
+			library, _ := library.(map[string]interface{})
+			parameters, _ := library["parameters"].(map[string]interface{})
+			relationships, _ := parameters["high-availability"].([]interface{})
+			for _, relationship := range relationships {
+				relationship, _ := relationship.(map[string]interface{})
+				self, _ := relationship["this-server-name"].(string)
+				servers, _ := relationship["peers"].([]interface{})
+				for _, server := range servers {
+					server, _ := server.(map[string]interface{})
+					name, _ := server["name"].(string)
+					peerURL, _ := server["url"].(string)
+					if name != self {
+						peers = append(peers, haPeer{Name: name, URL: peerURL})
+					}
+				}
+			}
+		}
+	}
+	return peers
+}
+
+// peerInstances resolves HA peers to the Kea instances running them: a peer
+// is matched by the host of its URL against an instance's control agent or
+// machine, or by its name against the instance name.
+func peerInstances(peers []haPeer, apps []KeaAppDetail) []string {
+	var instances []string
+	for _, peer := range peers {
+		host := ""
+		if u, err := url.Parse(peer.URL); err == nil {
+			host = u.Hostname()
+		}
+		for _, app := range apps {
+			hosts := []string{app.Machine.Address, app.Machine.Hostname}
+			for _, ap := range app.AccessPoints {
+				if ap.Type == "control" {
+					hosts = append(hosts, ap.Address)
+				}
+			}
+			match := strings.EqualFold(peer.Name, app.Name)
+			for _, h := range hosts {
+				if host != "" && strings.EqualFold(h, host) {
+					match = true
+				}
+			}
+			if match {
+				instances = append(instances, app.Name)
+				break
+			}
+		}
+	}
+	return instances
+}
+
+// leasePartners looks up the HA partners of the instances holding a copy of
+// a lease (only the given instance, if any), keyed by environment and
+// instance. An instance whose configuration can't be read is reported and
+// treated as having no partner, so that only its own copy is deleted.
+func leasePartners(copies []leaseCopy, instance string) map[string][]string {
+	partners := map[string][]string{}
+	apps := map[string][]KeaAppDetail{}
+	for _, c := range copies {
+		key := c.env.name + "/" + c.lease.AppName
+		if _, ok := partners[key]; ok || (instance != "" && !strings.EqualFold(c.lease.AppName, instance)) {
+			continue
+		}
+		partners[key] = nil
+
+		daemon := "dhcp6"
+		if ip := net.ParseIP(c.lease.IpAddress); ip != nil && ip.To4() != nil {
+			daemon = "dhcp4"
+		}
+		config, err := getDaemonConfig(c.lease.AppName, daemon, c.env.url, c.env.jar)
+		if err != nil {
+			fmt.Printf("%s: %s: could not read HA peers: %s\n", c.env.name, c.lease.AppName, err.Error())
+			continue
+		}
+		peers := haPeers(config.Config)
+		if len(peers) == 0 {
+			continue
+		}
+		if apps[c.env.name] == nil {
+			if apps[c.env.name], err = getAppDetails(c.env.url, c.env.jar); err != nil {
+				fmt.Printf("%s: %s\n", c.env.name, err.Error())
+				continue
+			}
+		}
+		partners[key] = peerInstances(peers, apps[c.env.name])
+	}
+	return partners
+}
+
+// describeCopies lists lease copies for an error message.
+func describeCopies(copies []leaseCopy) string {
+	var found []string
+	for _, c := range copies {
+		found = append(found, fmt.Sprintf("%s on %s/%s", c.lease.IpAddress, c.env.name, c.lease.AppName))
+	}
+	sort.Strings(found)
+	return strings.Join(found, ", ")
+}
+
+// selectLease narrows the matching leases down to one lease on one instance
+// (the given one, if any) and the copies its HA partners hold. Other
+// instances leasing the same address are left alone.
+func selectLease(copies []leaseCopy, instance string, partners map[string][]string) ([]leaseCopy, error) {
+	var chosen *leaseCopy
+	for i, c := range copies {
+		if instance != "" && !strings.EqualFold(c.lease.AppName, instance) {
+			continue
+		}
+		if chosen == nil {
+			chosen = &copies[i]
+		} else if c.lease.IpAddress != chosen.lease.IpAddress {
+			return nil, fmt.Errorf("several leases match on %s, delete one by IP address: %s", instance, describeCopies(copies))
+		}
+	}
+	if chosen == nil {
+		if instance != "" {
+			return nil, fmt.Errorf("no matching lease on %s", instance)
+		}
+		return nil, errors.New("no matching lease found")
+	}
+
+	peers := partners[chosen.env.name+"/"+chosen.lease.AppName]
+	var selected []leaseCopy
+	for _, c := range copies {
+		if c.env.name != chosen.env.name || c.lease.IpAddress != chosen.lease.IpAddress {
+			continue
+		}
+		holder := strings.EqualFold(c.lease.AppName, chosen.lease.AppName)
+		for _, peer := range peers {
+			holder = holder || strings.EqualFold(c.lease.AppName, peer)
+		}
+		if holder {
+			selected = append(selected, c)
+		}
+	}
+	if instance == "" && len(selected) < len(copies) {
+		return nil, fmt.Errorf("several leases match, pick one with --instance: %s", describeCopies(copies))
+	}
+	return selected, nil
+}
+
+func (r *LeaseRmCmd) Run() error {
+	if net.ParseIP(r.Lease) == nil {
+		if _, err := net.ParseMAC(r.Lease); err != nil {
+			return fmt.Errorf("%s is not an IP or MAC address", r.Lease)
+		}
+	}
+
+	envs, err := storkLogin()
+	if err != nil {
+		return err
+	}
+
+	copies, err := findLeaseCopies(r.Lease, envs)
+	if err != nil {
+		return err
+	}
+	selected, err := selectLease(copies, r.Instance, leasePartners(copies, r.Instance))
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetBorder(false)
+	table.SetHeader([]string{"Environment", "Kea Instance", "IP Address", "Hardware Address (MAC)", "Hostname", "Subnet ID", "Lease State", "Expires"})
+	for _, c := range selected {
+		table.Append([]string{
+			c.env.name,
+			c.lease.AppName,
+			c.lease.IpAddress,
+			c.lease.HwAddress,
+			c.lease.Hostname,
+			strconv.Itoa(c.lease.SubnetID),
+			c.lease.StateText(),
+			formatExpiry(c.lease.Cltt, c.lease.ValidLifetime),
+		})
+	}
+	fmt.Println()
+	table.Render()
+	fmt.Println()
+
+	if !r.Yes {
+		ok, err := confirm(fmt.Sprintf("Delete the lease of %s on %d instances?", selected[0].lease.IpAddress, len(selected)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Not deleting.")
+			return nil
+		}
+	}
+
+	failed := 0
+	for _, c := range selected {
+		app, err := getApp(c.lease.AppName, c.env.url, c.env.jar)
+		if err == nil {
+			err = deleteLease(app, c.lease.IpAddress)
+		}
+		switch {
+		case errors.Is(err, errKeaEmpty):
+			// The HA partner may already have dropped its copy.
+			fmt.Printf("%s: %s: lease already gone\n", c.env.name, c.lease.AppName)
+		case err != nil:
+			fmt.Printf("%s: %s: %s\n", c.env.name, c.lease.AppName, err.Error())
+			failed++
+		default:
+			fmt.Printf("%s: %s: deleted lease of %s\n", c.env.name, c.lease.AppName, c.lease.IpAddress)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("the lease could not be deleted on %d instances", failed)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectLease(t *testing.T) {
+	held := func(env, instance, ip string) leaseCopy {
+		return leaseCopy{env: stork{name: env}, lease: LeaseItem{AppName: instance, IpAddress: ip, HwAddress: "aa:bb:cc:00:00:01"}}
+	}
+	partners := map[string][]string{
+		"Production/NYC3": {"NYC4"},
+		"Production/NYC4": {"NYC3"},
+	}
+	tests := []struct {
+		name     string
+		copies   []leaseCopy
+		instance string
+		want     []string
+		err      string
+	}{
+		{
+			name:   "HA pair",
+			copies: []leaseCopy{held("Production", "NYC3", "10.0.0.5"), held("Production", "NYC4", "10.0.0.5")},
+			want:   []string{"NYC3 10.0.0.5", "NYC4 10.0.0.5"},
+		},
+		{
+			name:   "standalone",
+			copies: []leaseCopy{held("Production", "SFO1", "10.8.0.5")},
+			want:   []string{"SFO1 10.8.0.5"},
+		},
+		{
+			name:   "same IP on non-partner instances",
+			copies: []leaseCopy{held("Production", "NYC3", "10.0.0.5"), held("Production", "NYC4", "10.0.0.5"), held("Production", "SFO1", "10.0.0.5")},
+			err:    "several leases match, pick one with --instance",
+		},
+		{
+			name:     "instance with partner",
+			copies:   []leaseCopy{held("Production", "NYC3", "10.0.0.5"), held("Production", "NYC4", "10.0.0.5"), held("Production", "SFO1", "10.0.0.5")},
+			instance: "nyc4",
+			want:     []string{"NYC3 10.0.0.5", "NYC4 10.0.0.5"},
+		},
+		{
+			name:     "instance without partner",
+			copies:   []leaseCopy{held("Production", "NYC3", "10.0.0.5"), held("Production", "NYC4", "10.0.0.5"), held("Production", "SFO1", "10.0.0.5")},
+			instance: "SFO1",
+			want:     []string{"SFO1 10.0.0.5"},
+		},
+		{
+			name:     "instance matching nothing",
+			copies:   []leaseCopy{held("Production", "NYC3", "10.0.0.5"), held("Production", "NYC4", "10.0.0.5")},
+			instance: "SFO1",
+			err:      "no matching lease on SFO1",
+		},
+		{
+			name:     "partner in another environment",
+			copies:   []leaseCopy{held("Production", "NYC3", "10.0.0.5"), held("Stage2", "NYC4", "10.0.0.5")},
+			instance: "NYC3",
+			want:     []string{"NYC3 10.0.0.5"},
+		},
+		{
+			name:     "MAC with two addresses on the instance",
+			copies:   []leaseCopy{held("Production", "NYC3", "10.0.0.5"), held("Production", "NYC3", "10.0.0.6")},
+			instance: "NYC3",
+			err:      "several leases match on NYC3, delete one by IP address",
+		},
+		{
+			name: "nothing found",
+			err:  "no matching lease found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected, err := selectLease(test.copies, test.instance, partners)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range selected {
+				got = append(got, c.lease.AppName+" "+c.lease.IpAddress)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHAPeers(t *testing.T) {
+	config := testConfig(t, `{"Dhcp4": {"hooks-libraries": [
+		{"library": "/usr/lib/kea/hooks/libdhcp_lease_cmds.so"},
+		{"library": "/usr/lib/kea/hooks/libdhcp_ha.so", "parameters": {"high-availability": [{
+			"this-server-name": "nyc3",
+			"mode": "hot-standby",
+			"peers": [
+				{"name": "nyc3", "url": "http://10.1.0.3:8000/", "role": "primary"},
+				{"name": "nyc4", "url": "http://10.1.0.4:8000/", "role": "standby"}
+			]
+		}]}}
+	]}}`)
+	want := []haPeer{{Name: "nyc4", URL: "http://10.1.0.4:8000/"}}
+	if got := haPeers(config); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := haPeers(testConfig(t, `{"Dhcp4": {"hooks-libraries": []}}`)); got != nil {
+		t.Errorf("got %v without HA", got)
+	}
+}
+
+func TestPeerInstances(t *testing.T) {
+	var apps []KeaAppDetail
+	err := json.Unmarshal([]byte(`[
+		{"name": "NYC3", "machine": {"address": "10.1.0.3", "hostname": "kea-nyc3"}, "accessPoints": [{"type": "control", "address": "10.1.0.3", "port": 8000}]},
+		{"name": "NYC4", "machine": {"address": "agent-nyc4", "hostname": "kea-nyc4"}, "accessPoints": [{"type": "control", "address": "10.1.0.4", "port": 8000}]},
+		{"name": "SFO1", "machine": {"address": "10.8.0.1", "hostname": "kea-sfo1"}}
+	]`), &apps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		peers []haPeer
+		want  []string
+	}{
+		{name: "control agent address", peers: []haPeer{{Name: "server2", URL: "http://10.1.0.4:8000/"}}, want: []string{"NYC4"}},
+		{name: "machine hostname", peers: []haPeer{{Name: "server2", URL: "http://KEA-SFO1:8001/"}}, want: []string{"SFO1"}},
+		{name: "peer name", peers: []haPeer{{Name: "nyc3", URL: "http://192.0.2.1:8000/"}}, want: []string{"NYC3"}},
+		{name: "unknown", peers: []haPeer{{Name: "server9", URL: "http://192.0.2.9:8000/"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := peerInstances(test.peers, apps); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
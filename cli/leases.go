@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"net"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// LeasesCmd groups the commands working on leases in bulk.
+type LeasesCmd struct {
+	Declined LeasesDeclinedCmd `kong:"cmd='',help='List declined leases and optionally reclaim them'"`
+	Export   LeasesExportCmd   `kong:"cmd='',help='Export the leases of an instance or subnet'"`
+}
+
+type LeasesDeclinedCmd struct {
+	Selector string `kong:"arg='',optional,name='region-or-subnet',completion='regions',help='Kea instance or prefix (e.g. NYC3), subnet (e.g. 10.30.2.0/24) or Kea subnet ID'"`
+	Reclaim  bool   `kong:"optional,help='Delete the declined leases so the addresses can be handed out again.'"`
+	Yes      bool   `kong:"optional,short='y',help='Do not ask for confirmation before reclaiming.'"`
+}
+
+// leaseFilter selects leases by instance prefix, subnet prefix or Kea subnet
+// ID, as given on the command line.
+type leaseFilter struct {
+	instance string
+	network  *net.IPNet
+	subnetID int
+}
+
+func parseLeaseFilter(selector string) leaseFilter {
+	if _, network, err := net.ParseCIDR(selector); err == nil {
+		return leaseFilter{network: network}
+	}
+	if id, err := strconv.Atoi(selector); err == nil {
+		return leaseFilter{subnetID: id}
+	}
+	return leaseFilter{instance: selector}
+}
+
+// environments returns the environments that can hold matching leases.
+func (f leaseFilter) environments() (map[string]string, error) {
+	if f.instance == "" {
+		return environments, nil
+	}
+	envName, _, err := instanceEnvironment(f.instance)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{envName: environments[envName]}, nil
+}
+
+func (f leaseFilter) match(lease LeaseItem) bool {
+	switch {
+	case f.network != nil:
+		return f.network.Contains(net.ParseIP(lease.IpAddress))
+	case f.subnetID != 0:
+		return lease.SubnetID == f.subnetID
+	}
+	return matchesInstance(lease.AppName, f.instance)
+}
+
+// LastTransaction returns when the client last talked to the server about the
+// lease (Kea's cltt), or the zero time if Kea has none.
+func (l LeaseItem) LastTransaction() time.Time {
+	if l.Cltt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(l.Cltt, 0)
+}
+
+// Expiry returns when a lease expires. For a declined lease this is the end
+// of its probation period. It is the zero time if the lease has no
+// transaction time or never expires.
+func (l LeaseItem) Expiry() time.Time {
+	return leaseExpiry(l.Cltt, l.ValidLifetime)
+}
+
+// getDeclinedLeases returns every declined lease in an environment, using
+// Stork's lease search.
+func getDeclinedLeases(envURL *url.URL, jar *cookiejar.Jar) ([]LeaseItem, error) {
+	l, err := getLeases("state:declined", envURL, jar)
+	if err != nil {
+		return nil, err
+	}
+	return l.Items, nil
+}
+
+// deleteLease removes a lease from a Kea instance through its control agent.
+func deleteLease(app *KeaAppDetail, address string) error {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return fmt.Errorf("invalid IP address %s", address)
+	}
+
+	service, command := "dhcp4", "lease4-del"
+	if ip.To4() == nil {
+		service, command = "dhcp6", "lease6-del"
+	}
+	return keaCommand(app, service, command, map[string]string{"ip-address": address}, nil)
+}
+
+// declinedLeases picks the declined leases matching the filter, by instance
+// and then in the order they were declined. These are the leases --reclaim
+// deletes.
+func declinedLeases(leases []LeaseItem, filter leaseFilter) []LeaseItem {
+	var declined []LeaseItem
+	for _, lease := range leases {
+		if lease.State == leaseDeclined && filter.match(lease) {
+			declined = append(declined, lease)
+		}
+	}
+	sort.Slice(declined, func(i, j int) bool {
+		if declined[i].AppName != declined[j].AppName {
+			return declined[i].AppName < declined[j].AppName
+		}
+		return declined[i].Cltt < declined[j].Cltt
+	})
+	return declined
+}
+
+func (d *LeasesDeclinedCmd) Run() error {
+	filter := parseLeaseFilter(d.Selector)
+	selected, err := filter.environments()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for envName, environment := range selected {
+		envURL, err := url.Parse(environment)
+		if err != nil {
+			fmt.Printf("Error parsing environment URLs: %v", err.Error())
+			return err
+		}
+
+		jar, err := storkAuth(envURL)
+		if err != nil {
+			fmt.Printf("%s: %s\n", envName, err.Error())
+			continue
+		}
+
+		leases, err := getDeclinedLeases(envURL, jar)
+		if err != nil {
+			fmt.Printf("%s: %s\n", envName, err.Error())
+			continue
+		}
+
+		declined := declinedLeases(leases, filter)
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetAutoWrapText(false)
+		table.SetBorder(false)
+		// Kea forgets the client's MAC and client ID when it declines a
+		// lease, so there is no client to show.
+		table.SetHeader([]string{"Kea Instance", "IP Address", "Subnet ID", "Declined", "Probation Ends"})
+		for _, lease := range declined {
+			probation := formatTime(lease.Expiry())
+			switch {
+			case lease.Expiry().IsZero():
+			case time.Until(lease.Expiry()) > 0:
+				probation += " (" + relativeTime(lease.Expiry()) + ")"
+			default:
+				probation += " (ended)"
+			}
+
+			table.Append([]string{
+				lease.AppName,
+				lease.IpAddress,
+				strconv.Itoa(lease.SubnetID),
+				formatTime(lease.LastTransaction()),
+				probation,
+			})
+		}
+		fmt.Printf("\n%s: (%d declined leases)\n", envName, len(declined))
+		table.Render()
+
+		if !d.Reclaim || len(declined) == 0 {
+			continue
+		}
+
+		if !d.Yes {
+			ok, err := confirm(fmt.Sprintf("Delete %d declined leases in %s?", len(declined), envName))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Not reclaiming.")
+				continue
+			}
+		}
+
+		apps := map[string]*KeaAppDetail{}
+		reclaimed := 0
+		for _, lease := range declined {
+			app, ok := apps[lease.AppName]
+			if !ok {
+				app, err = getApp(lease.AppName, envURL, jar)
+				if err != nil {
+					fmt.Printf("%s: %s: %s\n", envName, lease.AppName, err.Error())
+					continue
+				}
+				apps[lease.AppName] = app
+			}
+
+			err = deleteLease(app, lease.IpAddress)
+			if err != nil {
+				fmt.Printf("%s: %s: %s: %s\n", envName, lease.AppName, lease.IpAddress, err.Error())
+				continue
+			}
+			reclaimed++
+		}
+		fmt.Printf("Reclaimed %d of %d declined leases in %s.\n", reclaimed, len(declined), envName)
+		failed += len(declined) - reclaimed
+	}
+	fmt.Println()
+
+	if failed > 0 {
+		return fmt.Errorf("%d declined leases could not be reclaimed", failed)
+	}
+	return nil
+}
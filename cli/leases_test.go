@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseLeaseFilter(t *testing.T) {
+	tests := []struct {
+		selector string
+		match    []string
+		skip     []string
+	}{
+		{selector: "", match: []string{"NYC3 10.30.2.4 12", "S2R8 10.9.0.1 3"}},
+		{selector: "nyc", match: []string{"NYC3 10.30.2.4 12", "NYC4 10.30.2.4 12"}, skip: []string{"S2R8 10.9.0.1 3"}},
+		{selector: "NYC3", match: []string{"NYC3 10.30.2.4 12"}, skip: []string{"NYC4 10.30.2.4 12"}},
+		{selector: "10.30.2.0/24", match: []string{"NYC3 10.30.2.4 12", "S2R8 10.30.2.255 3"}, skip: []string{"NYC3 10.30.3.4 12"}},
+		{selector: "10.30.2.9/24", match: []string{"NYC3 10.30.2.4 12"}},
+		{selector: "2001:db8::/64", match: []string{"NYC3 2001:db8::5 1"}, skip: []string{"NYC3 2001:db9::5 1", "NYC3 10.30.2.4 1"}},
+		{selector: "12", match: []string{"NYC3 10.30.2.4 12", "S2R8 10.9.0.1 12"}, skip: []string{"NYC3 10.30.2.4 3"}},
+	}
+	lease := func(text string) LeaseItem {
+		var l LeaseItem
+		if _, err := fmt.Sscan(text, &l.AppName, &l.IpAddress, &l.SubnetID); err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			filter := parseLeaseFilter(test.selector)
+			for _, text := range test.match {
+				if !filter.match(lease(text)) {
+					t.Errorf("%q does not match %s", test.selector, text)
+				}
+			}
+			for _, text := range test.skip {
+				if filter.match(lease(text)) {
+					t.Errorf("%q matches %s", test.selector, text)
+				}
+			}
+		})
+	}
+}
+
+func TestDeclinedLeases(t *testing.T) {
+	leases := []LeaseItem{
+		{AppName: "NYC4", IpAddress: "10.30.2.7", SubnetID: 12, State: leaseDeclined, Cltt: 300},
+		{AppName: "NYC3", IpAddress: "10.30.2.8", SubnetID: 12, State: leaseDeclined, Cltt: 200},
+		{AppName: "NYC3", IpAddress: "10.30.2.4", SubnetID: 12, State: leaseDefault, Cltt: 100},
+		{AppName: "NYC3", IpAddress: "10.30.2.5", SubnetID: 12, State: leaseReclaimed, Cltt: 100},
+		{AppName: "NYC3", IpAddress: "10.30.2.6", SubnetID: 12, State: leaseDeclined, Cltt: 100},
+		{AppName: "NYC3", IpAddress: "10.30.3.6", SubnetID: 13, State: leaseDeclined, Cltt: 50},
+		{AppName: "S2R8", IpAddress: "10.9.0.6", SubnetID: 12, State: leaseDeclined, Cltt: 10},
+	}
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{selector: "", want: []string{"NYC3/10.30.3.6", "NYC3/10.30.2.6", "NYC3/10.30.2.8", "NYC4/10.30.2.7", "S2R8/10.9.0.6"}},
+		{selector: "NYC", want: []string{"NYC3/10.30.3.6", "NYC3/10.30.2.6", "NYC3/10.30.2.8", "NYC4/10.30.2.7"}},
+		{selector: "10.30.2.0/24", want: []string{"NYC3/10.30.2.6", "NYC3/10.30.2.8", "NYC4/10.30.2.7"}},
+		{selector: "13", want: []string{"NYC3/10.30.3.6"}},
+		{selector: "SFO"},
+	}
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			var got []string
+			for _, lease := range declinedLeases(leases, parseLeaseFilter(test.selector)) {
+				got = append(got, lease.AppName+"/"+lease.IpAddress)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -3,14 +3,24 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
 )
 
 type LogsCmd struct {
-	LogsInstance string `kong:"arg='',name='kea-instance',help='e.g. NYC3, S2R8'"`
+	LogsInstance string   `kong:"arg='',name='kea-instance',completion='instances',help='e.g. NYC3, S2R8'"`
+	Level        string   `kong:"optional,short='l',default='debug',enum='debug,info,warn,error,fatal',help='Only show entries of at least this severity.'"`
+	Since        string   `kong:"optional,help='Only show entries from this time on: a duration before now (e.g. 30m), a time of day or a date and time (e.g. 2024-05-01T10:00).'"`
+	Until        string   `kong:"optional,help='Only show entries up to this time, in the same forms as --since.'"`
+	MsgID        []string `kong:"optional,name='msgid',help='Only show entries with these message IDs; a trailing * matches a prefix, e.g. DHCP4_PACKET_*.'"`
+	Format       string   `kong:"optional,short='f',default='text',enum='text,json',help='Output format: text or json.'"`
+	LogTZ        string   `kong:"optional,name='log-tz',env='DHCLI_LOG_TZ',help='Time zone the Kea servers log in, e.g. UTC or America/New_York; local time by default.'"`
 }
 
 type KeaLogEntry struct {
@@ -18,9 +28,187 @@ type KeaLogEntry struct {
 	Contents []string `json:"contents"`
 }
 
+// LogLine is a Kea log message split into its parts. Lines Kea's default
+// layout doesn't match, e.g. the continuation of a multi-line message, are
+// added to the text of the message before them.
+type LogLine struct {
+	Time      *time.Time `json:"time,omitempty"`
+	Severity  string     `json:"severity,omitempty"`
+	Logger    string     `json:"logger,omitempty"`
+	MessageID string     `json:"messageId,omitempty"`
+	Text      string     `json:"text"`
+}
+
+// Kea severities, least severe first.
+var logSeverities = []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+// logPattern matches Kea's default layout, e.g.
+//
+//	2024-05-01 10:32:15.123 INFO  [kea-dhcp4.dhcp4/1234.1401] DHCP4_STARTED Kea DHCPv4 server version 2.4.1 started
+var logPattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?) +(DEBUG|INFO|WARN|ERROR|FATAL)(?:\[\d+\])? +\[([^\]/]+)(?:/[^\]]*)?\] +([A-Z][A-Z0-9_]+)(?: +(.*))?$`)
+
+// parseLogLine splits a line in Kea's default layout into its parts. Kea
+// writes timestamps without a time zone; they are read in the time zone the
+// server logs in.
+func parseLogLine(line string, location *time.Location) (LogLine, bool) {
+	m := logPattern.FindStringSubmatch(line)
+	if m == nil {
+		return LogLine{Text: line}, false
+	}
+	entry := LogLine{Severity: m[2], Logger: m[3], MessageID: m[4], Text: m[5]}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05.999999999", m[1], location); err == nil {
+		entry.Time = &t
+	}
+	return entry, true
+}
+
+// parseLog parses log lines into messages, joining continuation lines.
+func parseLog(lines []string, location *time.Location) []LogLine {
+	var entries []LogLine
+	for _, line := range lines {
+		entry, ok := parseLogLine(line, location)
+		if !ok && len(entries) > 0 {
+			entries[len(entries)-1].Text += "\n" + line
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// severityRank orders severities, -1 for an unknown one.
+func severityRank(severity string) int {
+	for i, s := range logSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// severityColor returns the ANSI color a severity is highlighted in.
+func severityColor(severity string) string {
+	switch severity {
+	case "ERROR", "FATAL":
+		return ansiRed
+	case "WARN":
+		return ansiYellow
+	case "DEBUG":
+		return ansiDim
+	}
+	return ""
+}
+
+// parseLogTime reads a --since or --until value: a duration before now, a
+// date and time, or a time of day today, in the time zone the server logs
+// in, so that the value compares with the log as written.
+func parseLogTime(text string, now time.Time, location *time.Location) (time.Time, error) {
+	if d, err := time.ParseDuration(text); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, text, location); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, text, location); err == nil {
+			y, m, d := now.In(location).Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, location), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use a duration like 30m or a time like \"2024-05-01 10:00\"", text)
+}
+
+// logFilter selects log messages by severity, time and message ID.
+type logFilter struct {
+	minSeverity  int
+	since, until time.Time
+	msgIDs       []string
+}
+
+// logLocation returns the time zone the Kea servers log in, from --log-tz.
+// It is independent of --utc, which only changes how times are shown.
+func (l *LogsCmd) logLocation() (*time.Location, error) {
+	if l.LogTZ == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(l.LogTZ)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --log-tz: %w", err)
+	}
+	return location, nil
+}
+
+func (l *LogsCmd) filter(location *time.Location) (*logFilter, error) {
+	f := logFilter{minSeverity: severityRank(strings.ToUpper(l.Level))}
+	now := time.Now()
+	var err error
+	if l.Since != "" {
+		if f.since, err = parseLogTime(l.Since, now, location); err != nil {
+			return nil, err
+		}
+	}
+	if l.Until != "" {
+		if f.until, err = parseLogTime(l.Until, now, location); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range l.MsgID {
+		f.msgIDs = append(f.msgIDs, strings.ToUpper(id))
+	}
+	return &f, nil
+}
+
+func (f *logFilter) match(entry LogLine) bool {
+	if f.minSeverity > 0 && severityRank(entry.Severity) < f.minSeverity {
+		return false
+	}
+	if !f.since.IsZero() && (entry.Time == nil || entry.Time.Before(f.since)) {
+		return false
+	}
+	if !f.until.IsZero() && (entry.Time == nil || entry.Time.After(f.until)) {
+		return false
+	}
+	if len(f.msgIDs) == 0 {
+		return true
+	}
+	for _, pattern := range f.msgIDs {
+		if ok, _ := path.Match(pattern, entry.MessageID); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getLogEntries fetches the recent kea-dhcp4 log entries of a Kea instance.
+func getLogEntries(instance string, envURL *url.URL, jar *cookiejar.Jar) (*KeaLogEntry, error) {
+	logURL := *envURL
+	logId, err := getLogID("kea-dhcp4", instance, &logURL, jar)
+	if err != nil {
+		return nil, err
+	}
+
+	k := KeaLogEntry{}
+	err = storkGet(fmt.Sprintf("/api/logs/%d", logId), url.Values{}, envURL, jar, &k)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching log entries: %w", err)
+	}
+	return &k, nil
+}
+
 func (l *LogsCmd) Run() error {
 	searchInstance := l.LogsInstance
 
+	location, err := l.logLocation()
+	if err != nil {
+		return err
+	}
+	filter, err := l.filter(location)
+	if err != nil {
+		return err
+	}
+
 	var envName = "Production"
 	var envURL = environments[envName]
 
@@ -40,47 +228,45 @@ func (l *LogsCmd) Run() error {
 		return err
 	}
 
-	logURL, _ := url.Parse(envURL)
-	logId, err := getLogID("kea-dhcp4", searchInstance, logURL, jar)
+	k, err := getLogEntries(searchInstance, storkURL, jar)
 	if err != nil {
 		fmt.Printf("%s: %s: %s\n", envName, searchInstance, err.Error())
 		return err
 	}
 
-	logURL.Path = fmt.Sprintf("/api/logs/%d", logId)
-
-	client := &http.Client{Jar: jar}
-	resp, err := client.Get(logURL.String())
-	if err != nil {
-		fmt.Println(err.Error())
-		return err
+	entries := []LogLine{}
+	for _, entry := range parseLog(k.Contents, location) {
+		if filter.match(entry) {
+			entries = append(entries, entry)
+		}
 	}
 
-	if resp.StatusCode != 200 {
-		fmt.Printf("Error: Stork returned %d on search!", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println(err)
-		return err
-	}
-
-	defer resp.Body.Close()
-
-	if len(body) == 0 {
-		fmt.Printf("Unexpected output from Stork server: %s", err.Error())
-	}
-
-	k := KeaLogEntry{}
-	jsonErr := json.Unmarshal(body, &k)
-	if jsonErr != nil {
-		return jsonErr
+	if l.Format == "json" {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
 	}
 
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
 	fmt.Printf("%s: Recent Log Entries\n", envName)
-	for _, value := range k.Contents {
-		fmt.Printf("%s\n", value)
+	for _, entry := range entries {
+		if entry.Severity == "" {
+			fmt.Println(entry.Text)
+			continue
+		}
+
+		timestamp := ""
+		if entry.Time != nil {
+			timestamp = entry.Time.In(displayLocation).Format("2006-01-02 15:04:05.000") + " "
+		}
+		line := fmt.Sprintf("%s%-5s [%s] %s %s", timestamp, entry.Severity, entry.Logger, entry.MessageID, entry.Text)
+		if c := severityColor(entry.Severity); color && c != "" {
+			line = c + line + ansiReset
+		}
+		fmt.Println(line)
 	}
 	return nil
 }
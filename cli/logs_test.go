@@ -0,0 +1,253 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want LogLine
+		time string
+		ok   bool
+	}{
+		{
+			name: "info",
+			line: "2024-05-01 10:32:15.123 INFO  [kea-dhcp4.dhcp4/1234.1401] DHCP4_STARTED Kea DHCPv4 server version 2.4.1 started",
+			want: LogLine{Severity: "INFO", Logger: "kea-dhcp4.dhcp4", MessageID: "DHCP4_STARTED", Text: "Kea DHCPv4 server version 2.4.1 started"},
+			time: "2024-05-01T10:32:15.123Z",
+			ok:   true,
+		},
+		{
+			name: "debug level",
+			line: "2024-05-01 10:32:15.123 DEBUG[45] [kea-dhcp4.packets/1234.1401] DHCP4_BUFFER_RECEIVED received buffer",
+			want: LogLine{Severity: "DEBUG", Logger: "kea-dhcp4.packets", MessageID: "DHCP4_BUFFER_RECEIVED", Text: "received buffer"},
+			time: "2024-05-01T10:32:15.123Z",
+			ok:   true,
+		},
+		{
+			name: "no fraction or text",
+			line: "2024-05-01 10:32:15 WARN [kea-dhcp6.dhcp6] DHCP6_SHUTDOWN",
+			want: LogLine{Severity: "WARN", Logger: "kea-dhcp6.dhcp6", MessageID: "DHCP6_SHUTDOWN"},
+			time: "2024-05-01T10:32:15Z",
+			ok:   true,
+		},
+		{
+			name: "continuation",
+			line: "    at line 12",
+			want: LogLine{Text: "    at line 12"},
+		},
+		{
+			name: "unknown severity",
+			line: "2024-05-01 10:32:15 NOTICE [kea-dhcp4.dhcp4/1] DHCP4_STARTED started",
+			want: LogLine{Text: "2024-05-01 10:32:15 NOTICE [kea-dhcp4.dhcp4/1] DHCP4_STARTED started"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := parseLogLine(test.line, time.UTC)
+			if ok != test.ok {
+				t.Fatalf("ok = %t, want %t", ok, test.ok)
+			}
+			gotTime := ""
+			if got.Time != nil {
+				gotTime = got.Time.Format(time.RFC3339Nano)
+			}
+			if gotTime != test.time {
+				t.Errorf("time %s, want %s", gotTime, test.time)
+			}
+			got.Time = nil
+			if got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseLog(t *testing.T) {
+	entries := parseLog([]string{
+		"2024-05-01 10:32:15.123 ERROR [kea-dhcp4.dhcp4/1] DHCP4_CONFIG_LOAD_FAIL configuration error",
+		"  subnet overlaps",
+		"2024-05-01 10:32:16.000 INFO  [kea-dhcp4.dhcp4/1] DHCP4_STARTED started",
+	}, time.UTC)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if want := "configuration error\n  subnet overlaps"; entries[0].Text != want {
+		t.Errorf("got %q, want %q", entries[0].Text, want)
+	}
+}
+
+func TestParseLogTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want string
+		err  string
+	}{
+		{in: "30m", want: "2024-05-01T11:30:00Z"},
+		{in: "2h15m", want: "2024-05-01T09:45:00Z"},
+		{in: "2024-04-30 08:15:30", want: "2024-04-30T08:15:30Z"},
+		{in: "2024-04-30 08:15", want: "2024-04-30T08:15:00Z"},
+		{in: "2024-04-30T08:15", want: "2024-04-30T08:15:00Z"},
+		{in: "2024-04-30", want: "2024-04-30T00:00:00Z"},
+		{in: "10:00", want: "2024-05-01T10:00:00Z"},
+		{in: "10:00:05", want: "2024-05-01T10:00:05Z"},
+		{in: "yesterday", err: `invalid time "yesterday"`},
+		{in: "2024-13-01", err: "invalid time"},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			got, err := parseLogTime(test.in, now, time.UTC)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("got %v, want error %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Format(time.RFC3339) != test.want {
+				t.Errorf("got %s, want %s", got.Format(time.RFC3339), test.want)
+			}
+		})
+	}
+}
+
+func TestLogFilterMatch(t *testing.T) {
+	at := func(s string) *time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return &t
+	}
+	since, until := *at("2024-05-01T10:00:00Z"), *at("2024-05-01T11:00:00Z")
+	started := LogLine{Time: at("2024-05-01T10:30:00Z"), Severity: "INFO", MessageID: "DHCP4_STARTED"}
+	failed := LogLine{Time: at("2024-05-01T10:30:00Z"), Severity: "ERROR", MessageID: "DHCP4_CONFIG_LOAD_FAIL"}
+	early := LogLine{Time: at("2024-05-01T09:59:59Z"), Severity: "ERROR", MessageID: "DHCP4_STARTED"}
+	late := LogLine{Time: at("2024-05-01T11:00:01Z"), Severity: "ERROR", MessageID: "DHCP4_STARTED"}
+	untimed := LogLine{Text: "plain line"}
+
+	tests := []struct {
+		name   string
+		filter logFilter
+		entry  LogLine
+		want   bool
+	}{
+		{name: "no filter", filter: logFilter{}, entry: untimed, want: true},
+		{name: "severity below", filter: logFilter{minSeverity: severityRank("WARN")}, entry: started, want: false},
+		{name: "severity above", filter: logFilter{minSeverity: severityRank("WARN")}, entry: failed, want: true},
+		{name: "unknown severity filtered", filter: logFilter{minSeverity: severityRank("WARN")}, entry: untimed, want: false},
+		{name: "debug keeps all", filter: logFilter{minSeverity: severityRank("DEBUG")}, entry: untimed, want: true},
+		{name: "in window", filter: logFilter{since: since, until: until}, entry: started, want: true},
+		{name: "before since", filter: logFilter{since: since}, entry: early, want: false},
+		{name: "after until", filter: logFilter{until: until}, entry: late, want: false},
+		{name: "untimed with since", filter: logFilter{since: since}, entry: untimed, want: false},
+		{name: "exact msgid", filter: logFilter{msgIDs: []string{"DHCP4_STARTED"}}, entry: started, want: true},
+		{name: "msgid glob", filter: logFilter{msgIDs: []string{"DHCP4_CONFIG_*"}}, entry: failed, want: true},
+		{name: "msgid no match", filter: logFilter{msgIDs: []string{"DHCP4_CONFIG_*"}}, entry: started, want: false},
+		{name: "any msgid", filter: logFilter{msgIDs: []string{"DHCP6_*", "DHCP4_STARTED"}}, entry: started, want: true},
+		{name: "all conditions", filter: logFilter{minSeverity: severityRank("ERROR"), since: since, msgIDs: []string{"DHCP4_*"}}, entry: failed, want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.filter.match(test.entry); got != test.want {
+				t.Errorf("got %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLogsCmdFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  LogsCmd
+		err  string
+	}{
+		{name: "level and msgid", cmd: LogsCmd{Level: "warn", MsgID: []string{"dhcp4_*"}}},
+		{name: "bad since", cmd: LogsCmd{Since: "soon"}, err: `invalid time "soon"`},
+		{name: "bad until", cmd: LogsCmd{Until: "later"}, err: `invalid time "later"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := test.cmd.filter(time.UTC)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("got %v, want error %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.minSeverity != severityRank("WARN") || len(f.msgIDs) != 1 || f.msgIDs[0] != "DHCP4_*" {
+				t.Errorf("got %+v", f)
+			}
+		})
+	}
+}
+
+func TestParseLogLineServerZone(t *testing.T) {
+	newYork := time.FixedZone("EDT", -4*3600)
+	entry, ok := parseLogLine("2024-05-01 10:32:15.000 INFO  [kea-dhcp4.dhcp4/1] DHCP4_STARTED started", newYork)
+	if !ok || entry.Time == nil {
+		t.Fatal("line not parsed")
+	}
+	if got := entry.Time.UTC().Format(time.RFC3339); got != "2024-05-01T14:32:15Z" {
+		t.Errorf("got %s, want 2024-05-01T14:32:15Z", got)
+	}
+}
+
+func TestLogLocation(t *testing.T) {
+	location, err := (&LogsCmd{}).logLocation()
+	if err != nil || location != time.Local {
+		t.Errorf("got %v, %v, want local time", location, err)
+	}
+	location, err = (&LogsCmd{LogTZ: "UTC"}).logLocation()
+	if err != nil || location != time.UTC {
+		t.Errorf("got %v, %v, want UTC", location, err)
+	}
+	if _, err = (&LogsCmd{LogTZ: "Nowhere/Special"}).logLocation(); err == nil || !strings.Contains(err.Error(), "invalid --log-tz") {
+		t.Errorf("got %v, want invalid --log-tz", err)
+	}
+}
+
+// --utc changes how times are shown, never which entries are kept.
+func TestLogFilterIgnoresDisplayZone(t *testing.T) {
+	saved := displayLocation
+	t.Cleanup(func() { displayLocation = saved })
+
+	lines := []string{
+		"2024-05-01 08:59:59.000 INFO  [kea-dhcp4.dhcp4/1] DHCP4_STARTED too early",
+		"2024-05-01 09:30:00.000 INFO  [kea-dhcp4.dhcp4/1] DHCP4_STARTED kept",
+		"2024-05-01 10:00:01.000 INFO  [kea-dhcp4.dhcp4/1] DHCP4_STARTED too late",
+	}
+	server := time.FixedZone("EDT", -4*3600)
+	cmd := LogsCmd{Since: "2024-05-01 09:00", Until: "2024-05-01 10:00"}
+
+	kept := func() []string {
+		filter, err := cmd.filter(server)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var texts []string
+		for _, entry := range parseLog(lines, server) {
+			if filter.match(entry) {
+				texts = append(texts, entry.Text)
+			}
+		}
+		return texts
+	}
+
+	SetUTC(false)
+	displayLocation = time.FixedZone("JST", 9*3600)
+	local := kept()
+	SetUTC(true)
+	utc := kept()
+	if !reflect.DeepEqual(local, []string{"kept"}) || !reflect.DeepEqual(utc, local) {
+		t.Errorf("kept %q in local time and %q in UTC, want [\"kept\"] both times", local, utc)
+	}
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// machineStaleAfter is how long since Stork last reached an agent before the
+// machine is flagged as unreachable.
+const machineStaleAfter = 5 * time.Minute
+
+type MachinesCmd struct {
+	Env      string `kong:"optional,short='e',help='Only list machines in this environment (Production or Stage2).'"`
+	Problems bool   `kong:"optional,short='p',help='Only list unreachable or unauthorized machines.'"`
+}
+
+type StorkMachine struct {
+	ID              int       `json:"id"`
+	Address         string    `json:"address"`
+	AgentPort       int       `json:"agentPort"`
+	AgentVersion    string    `json:"agentVersion"`
+	Authorized      bool      `json:"authorized"`
+	Hostname        string    `json:"hostname"`
+	Os              string    `json:"os"`
+	Platform        string    `json:"platform"`
+	PlatformVersion string    `json:"platformVersion"`
+	Cpus            int       `json:"cpus"`
+	CpusLoad        string    `json:"cpusLoad"`
+	Memory          int       `json:"memory"`
+	UsedMemory      int       `json:"usedMemory"`
+	Uptime          int       `json:"uptime"` // days
+	LastVisitedAt   time.Time `json:"lastVisitedAt"`
+	Error           string    `json:"error"`
+}
+
+// Problem describes why a machine needs attention, or returns "".
+func (m *StorkMachine) Problem() string {
+	switch {
+	case !m.Authorized:
+		return "UNAUTHORIZED"
+	case m.Error != "":
+		return "UNREACHABLE: " + m.Error
+	case m.LastVisitedAt.IsZero():
+		return "UNREACHABLE: never visited"
+	case time.Since(m.LastVisitedAt) > machineStaleAfter:
+		return "UNREACHABLE: not visited for " + time.Since(m.LastVisitedAt).Round(time.Minute).String()
+	}
+	return ""
+}
+
+// getMachines fetches the authorized and unauthorized machines running a
+// Stork agent.
+func getMachines(envURL *url.URL, jar *cookiejar.Jar) ([]StorkMachine, error) {
+	var machines []StorkMachine
+	for _, authorized := range []string{"true", "false"} {
+		query := url.Values{}
+		query.Set("authorized", authorized)
+		page, err := getAllPages[StorkMachine]("/api/machines", query, envURL, jar)
+		if err != nil {
+			return nil, fmt.Errorf("error listing machines: %w", err)
+		}
+		machines = append(machines, page...)
+	}
+	return machines, nil
+}
+
+func (m *MachinesCmd) Run() error {
+	selected, err := selectEnvironments(m.Env)
+	if err != nil {
+		return err
+	}
+
+	for _, envName := range environmentNames(selected) {
+		envURL, err := url.Parse(selected[envName])
+		if err != nil {
+			fmt.Printf("Error parsing environment URLs: %v", err.Error())
+			return err
+		}
+
+		jar, err := storkAuth(envURL)
+		if err != nil {
+			fmt.Printf("%s: %s\n", envName, err.Error())
+			continue
+		}
+
+		machines, err := getMachines(envURL, jar)
+		if err != nil {
+			fmt.Printf("%s: %s\n", envName, err.Error())
+			continue
+		}
+		sort.Slice(machines, func(i, j int) bool { return machines[i].Hostname < machines[j].Hostname })
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetAutoWrapText(false)
+		table.SetBorder(false)
+		table.SetHeader([]string{"Host", "Agent Address", "Agent Version", "Authorized", "Last Visited", "OS", "CPU (load)", "Memory", "Uptime", "Problem"})
+		count, problems := 0, 0
+		for _, machine := range machines {
+			problem := machine.Problem()
+			if problem != "" {
+				problems++
+			} else if m.Problems {
+				continue
+			}
+
+			lastVisited := "never"
+			if !machine.LastVisitedAt.IsZero() {
+				lastVisited = formatTime(machine.LastVisitedAt)
+			}
+			osName := strings.TrimSpace(fmt.Sprintf("%s %s", machine.Platform, machine.PlatformVersion))
+			if osName == "" {
+				osName = machine.Os
+			}
+
+			table.Append([]string{
+				strings.Replace(machine.Hostname, ".internal.digitalocean.com", "", 1),
+				fmt.Sprintf("%s:%d", machine.Address, machine.AgentPort),
+				machine.AgentVersion,
+				strconv.FormatBool(machine.Authorized),
+				lastVisited,
+				osName,
+				fmt.Sprintf("%d (%s)", machine.Cpus, machine.CpusLoad),
+				fmt.Sprintf("%d GiB (%d%% used)", machine.Memory, machine.UsedMemory),
+				fmt.Sprintf("%d days", machine.Uptime),
+				problem,
+			})
+			count++
+		}
+		fmt.Printf("\n%s: (%d machines, %d with problems)\n", envName, count, problems)
+		table.Render()
+	}
+	fmt.Println()
+	return nil
+}
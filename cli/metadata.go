@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"errors"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// storkPageSize is the number of items requested per page when walking a
+// Stork listing.
+const storkPageSize = 100
+
+var (
+	// metadataTTL is how long cached app and subnet listings are trusted.
+	metadataTTL = time.Hour
+	// refreshMetadata bypasses the metadata cache for this run.
+	refreshMetadata = false
+
+	// errNotFound is returned when Stork answers 404 for an object.
+	errNotFound = errors.New("not found")
+)
+
+// SetCacheOptions controls the app and subnet metadata cache. A refresh
+// ignores (and rewrites) any cached listings.
+func SetCacheOptions(ttl time.Duration, refresh bool) {
+	metadataTTL = ttl
+	refreshMetadata = refresh
+}
+
+func appsCacheName(envName string) string {
+	return "apps-" + envName
+}
+
+func subnetsCacheName(envName string) string {
+	return "subnets-" + envName
+}
+
+// invalidateMetadata drops the cached listings for an environment, e.g. after
+// a cached ID turned out to be stale.
+func invalidateMetadata(envName string) {
+	for _, name := range []string{appsCacheName(envName), subnetsCacheName(envName)} {
+		if path, err := cachePath(name); err == nil {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// retryStale runs a lookup filtered by a cached app or subnet ID. Stork
+// answers a filter on an ID it no longer knows with an empty listing rather
+// than a 404, so an empty result drops the cached listings and runs the
+// lookup, ID lookup included, once more against Stork.
+func retryStale[T any](envURL *url.URL, lookup func() ([]T, error)) ([]T, error) {
+	items, err := lookup()
+	if err != nil || len(items) > 0 || refreshMetadata {
+		return items, err
+	}
+	invalidateMetadata(environmentName(envURL))
+	refreshMetadata = true
+	return lookup()
+}
+
+// storkPage is one page of a Stork listing.
+type storkPage[T any] struct {
+	Total int `json:"total"`
+	Items []T `json:"items"`
+}
+
+// getAllPages walks every page of a Stork listing.
+func getAllPages[T any](path string, query url.Values, envURL *url.URL, jar *cookiejar.Jar) ([]T, error) {
+	var items []T
+	for {
+		values := url.Values{}
+		for key, value := range query {
+			values[key] = value
+		}
+		values.Set("start", strconv.Itoa(len(items)))
+		values.Set("limit", strconv.Itoa(storkPageSize))
+
+		page := storkPage[T]{}
+		err := storkGet(path, values, envURL, jar, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, page.Items...)
+		if len(page.Items) == 0 || len(items) >= page.Total {
+			return items, nil
+		}
+	}
+}
+
+// cachedListing returns a cached listing if it is fresh, otherwise fetches
+// it with fetch and caches the result.
+func cachedListing[T any](name string, fetch func() ([]T, error)) ([]T, error) {
+	cached := storkPage[T]{}
+	if !refreshMetadata {
+		written, err := readCache(name, &cached)
+		if err == nil && time.Since(written) < metadataTTL {
+			return cached.Items, nil
+		}
+	}
+
+	items, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed write only costs a round trip next time.
+	_ = writeCache(name, &storkPage[T]{Total: len(items), Items: items})
+	return items, nil
+}
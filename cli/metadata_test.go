@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRetryStale(t *testing.T) {
+	tests := []struct {
+		name    string
+		refresh bool
+		answers [][]int
+		err     error
+		want    []int
+		calls   int
+	}{
+		{name: "found", answers: [][]int{{1, 2}}, want: []int{1, 2}, calls: 1},
+		{name: "stale ID", answers: [][]int{nil, {3}}, want: []int{3}, calls: 2},
+		{name: "really empty", answers: [][]int{nil, nil}, calls: 2},
+		{name: "already fresh", refresh: true, answers: [][]int{nil}, calls: 1},
+		{name: "error", answers: [][]int{nil}, err: errors.New("boom"), calls: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			saved := refreshMetadata
+			refreshMetadata = test.refresh
+			t.Cleanup(func() { refreshMetadata = saved })
+
+			envURL, _ := url.Parse("https://stork.example.com")
+			if err := writeCache(appsCacheName(environmentName(envURL)), &storkPage[int]{}); err != nil {
+				t.Fatal(err)
+			}
+
+			calls := 0
+			got, err := retryStale(envURL, func() ([]int, error) {
+				calls++
+				return test.answers[calls-1], test.err
+			})
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+			if !reflect.DeepEqual(got, test.want) || calls != test.calls {
+				t.Errorf("got %v after %d calls, want %v after %d", got, calls, test.want, test.calls)
+			}
+
+			path, _ := cachePath(appsCacheName(environmentName(envURL)))
+			_, statErr := os.Stat(path)
+			if invalidated := os.IsNotExist(statErr); invalidated != (test.calls > 1) {
+				t.Errorf("cache invalidated: %v", invalidated)
+			}
+		})
+	}
+}
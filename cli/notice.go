@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"bytes"
+	"do/doge/version"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// updateCheckTimeout bounds the background manifest fetch so a slow
+// artifacts server never holds up the command the user actually ran.
+const updateCheckTimeout = 2 * time.Second
+
+type updateCheckState struct {
+	Checked time.Time `json:"checked"`
+	Channel string    `json:"channel"`
+	Version string    `json:"version"`
+}
+
+func updateCheckStatePath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "update-check.json"), nil
+}
+
+func readUpdateCheckState() updateCheckState {
+	state := updateCheckState{}
+	path, err := updateCheckStatePath()
+	if err != nil {
+		return state
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+	_ = json.Unmarshal(data, &state)
+	return state
+}
+
+func writeUpdateCheckState(state updateCheckState) {
+	path, err := updateCheckStatePath()
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0o600)
+}
+
+// fetchLatestVersion reads the version from a channel's manifest without
+// retries, unlike Update which is happy to wait.
+func fetchLatestVersion(channel string) (string, error) {
+	client := &http.Client{Timeout: updateCheckTimeout}
+	resp, err := client.Get(channelBaseURL(channel) + "packing_slip.json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("artifacts server returned %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var m struct {
+		Version string `json:"version"`
+	}
+	err = json.Unmarshal(bytes.TrimSpace(body), &m)
+	if err != nil {
+		return "", err
+	}
+	return m.Version, nil
+}
+
+// updateNotice describes a newer release recorded for a channel, or returns
+// "" if the running binary is current or nothing is known.
+func updateNotice(state updateCheckState, channel string, current string) string {
+	if current == "" || state.Channel != channel || state.Version == "" || state.Version == current {
+		return ""
+	}
+	return fmt.Sprintf(
+		"A new version of %s is available (%.7s, running %.7s); run '%s update' to upgrade.",
+		BinaryName, state.Version, current, BinaryName,
+	)
+}
+
+// UpdateCheck is a check for a newer release started by CheckForUpdate.
+type UpdateCheck struct {
+	notice string
+	done   chan struct{}
+}
+
+// CheckForUpdate looks for a newer release on a channel. The notice comes
+// from the version recorded by an earlier check, so it never waits on the
+// network. When that record is older than interval, the manifest is fetched
+// again in the background and the result saved for the next run.
+func CheckForUpdate(interval time.Duration, channel string) *UpdateCheck {
+	current := version.GetCommit()
+	state := readUpdateCheckState()
+	check := &UpdateCheck{notice: updateNotice(state, channel, current), done: make(chan struct{})}
+	if current == "" || (time.Since(state.Checked) < interval && state.Channel == channel) {
+		close(check.done)
+		return check
+	}
+
+	go func() {
+		defer close(check.done)
+		// Record the attempt even on failure so an unreachable artifacts
+		// server isn't retried on every invocation.
+		state := updateCheckState{Checked: time.Now(), Channel: channel}
+		if latest, err := fetchLatestVersion(channel); err == nil {
+			state.Version = latest
+		}
+		writeUpdateCheckState(state)
+	}()
+	return check
+}
+
+// PrintUpdateNotice writes the notice from CheckForUpdate to stderr, then
+// waits for a background check to save its result. The wait is bounded by
+// updateCheckTimeout and happens at most once per check interval.
+func PrintUpdateNotice(check *UpdateCheck) {
+	if check == nil {
+		return
+	}
+	if check.notice != "" {
+		fmt.Fprintln(os.Stderr, check.notice)
+	}
+	<-check.done
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateNotice(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   updateCheckState
+		current string
+		want    string
+	}{
+		{name: "newer", state: updateCheckState{Channel: "production", Version: "bbbbbbbbbb"}, current: "aaaaaaaaaa", want: "(bbbbbbb, running aaaaaaa)"},
+		{name: "current", state: updateCheckState{Channel: "production", Version: "aaaaaaaaaa"}, current: "aaaaaaaaaa"},
+		{name: "other channel", state: updateCheckState{Channel: "staging", Version: "bbbbbbbbbb"}, current: "aaaaaaaaaa"},
+		{name: "never checked", current: "aaaaaaaaaa"},
+		{name: "development build", state: updateCheckState{Channel: "production", Version: "bbbbbbbbbb"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := updateNotice(test.state, "production", test.current)
+			if test.want == "" && got != "" || !strings.Contains(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUpdateCheckStateRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	if state := readUpdateCheckState(); !state.Checked.IsZero() || state.Version != "" {
+		t.Fatalf("got %+v from an empty cache", state)
+	}
+	saved := updateCheckState{Checked: time.Now().Truncate(time.Second), Channel: "staging", Version: "bbbbbbbbbb"}
+	writeUpdateCheckState(saved)
+	if got := readUpdateCheckState(); !got.Checked.Equal(saved.Checked) || got.Channel != saved.Channel || got.Version != saved.Version {
+		t.Errorf("got %+v, want %+v", got, saved)
+	}
+}
@@ -0,0 +1,303 @@
+package cli
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// KeaOption is a DHCP option of a host reservation as reported by Stork.
+type KeaOption struct {
+	Code     int `json:"code"`
+	Universe int `json:"universe"`
+	Fields   []struct {
+		FieldType string   `json:"fieldType"`
+		Values    []string `json:"values"`
+	} `json:"fields"`
+	Options []KeaOption `json:"options"`
+}
+
+// Option value types of the built-in dictionary.
+const (
+	optBinary = iota
+	optString
+	optIPv4
+	optIPv4List
+	optIPv6List
+	optUint8
+	optUint16
+	optUint16List
+	optUint32
+	optInt32
+	optBool
+	optFqdn
+	optFqdnList
+	optClasslessRoutes
+)
+
+type optionDef struct {
+	name string
+	kind int
+}
+
+// dhcp4Options are the standard DHCPv4 options (RFC 2132 and later), named
+// as in Kea.
+var dhcp4Options = map[int]optionDef{
+	1:   {"subnet-mask", optIPv4},
+	2:   {"time-offset", optInt32},
+	3:   {"routers", optIPv4List},
+	4:   {"time-servers", optIPv4List},
+	5:   {"name-servers", optIPv4List},
+	6:   {"domain-name-servers", optIPv4List},
+	7:   {"log-servers", optIPv4List},
+	12:  {"host-name", optString},
+	15:  {"domain-name", optString},
+	17:  {"root-path", optString},
+	19:  {"ip-forwarding", optBool},
+	23:  {"default-ip-ttl", optUint8},
+	26:  {"interface-mtu", optUint16},
+	28:  {"broadcast-address", optIPv4},
+	33:  {"static-routes", optIPv4List},
+	42:  {"ntp-servers", optIPv4List},
+	43:  {"vendor-encapsulated-options", optBinary},
+	44:  {"netbios-name-servers", optIPv4List},
+	46:  {"netbios-node-type", optUint8},
+	47:  {"netbios-scope", optString},
+	51:  {"dhcp-lease-time", optUint32},
+	54:  {"dhcp-server-identifier", optIPv4},
+	58:  {"dhcp-renewal-time", optUint32},
+	59:  {"dhcp-rebinding-time", optUint32},
+	60:  {"vendor-class-identifier", optString},
+	61:  {"dhcp-client-identifier", optBinary},
+	64:  {"nisplus-domain-name", optString},
+	66:  {"tftp-server-name", optString},
+	67:  {"boot-file-name", optString},
+	69:  {"smtp-server", optIPv4List},
+	70:  {"pop-server", optIPv4List},
+	72:  {"www-server", optIPv4List},
+	77:  {"user-class", optBinary},
+	82:  {"dhcp-agent-options", optBinary},
+	93:  {"client-system", optUint16List},
+	94:  {"client-ndi", optBinary},
+	97:  {"uuid-guid", optBinary},
+	100: {"pcode", optString},
+	101: {"tcode", optString},
+	114: {"v4-captive-portal", optString},
+	119: {"domain-search", optFqdnList},
+	121: {"classless-static-route", optClasslessRoutes},
+	125: {"vivso-suboptions", optBinary},
+	150: {"tftp-server-address", optIPv4List},
+}
+
+// dhcp6Options are the standard DHCPv6 options, named as in Kea.
+var dhcp6Options = map[int]optionDef{
+	7:  {"preference", optUint8},
+	15: {"user-class", optBinary},
+	16: {"vendor-class", optBinary},
+	17: {"vendor-opts", optBinary},
+	21: {"sip-server-dns", optFqdnList},
+	22: {"sip-server-addr", optIPv6List},
+	23: {"dns-servers", optIPv6List},
+	24: {"domain-search", optFqdnList},
+	27: {"nis-servers", optIPv6List},
+	28: {"nisp-servers", optIPv6List},
+	29: {"nis-domain-name", optFqdnList},
+	31: {"sntp-servers", optIPv6List},
+	32: {"information-refresh-time", optUint32},
+	59: {"bootfile-url", optString},
+	60: {"bootfile-param", optBinary},
+	61: {"client-arch-type", optUint16List},
+	64: {"aftr-name", optFqdn},
+}
+
+// optionDefinition looks an option up in the dictionary of its universe.
+func optionDefinition(universe int, code int) (optionDef, bool) {
+	if universe == 6 {
+		def, ok := dhcp6Options[code]
+		return def, ok
+	}
+	def, ok := dhcp4Options[code]
+	return def, ok
+}
+
+// Name returns the option's standard name, or option-<code>.
+func (o *KeaOption) Name() string {
+	if def, ok := optionDefinition(o.Universe, o.Code); ok {
+		return def.name
+	}
+	return fmt.Sprintf("option-%d", o.Code)
+}
+
+// Value renders the option's value. Stork hands over fields it couldn't type
+// as hex, which are decoded with the dictionary; unknown options stay hex.
+func (o *KeaOption) Value() string {
+	return o.value(true)
+}
+
+// value renders the option's value, decoding hex fields with the dictionary
+// if standard is set. Suboptions are numbered within their option, so their
+// hex fields are never looked up in it.
+func (o *KeaOption) value(standard bool) string {
+	var parts []string
+	for _, field := range o.Fields {
+		if field.FieldType != "hex-bytes" && field.FieldType != "binary" {
+			parts = append(parts, strings.Join(field.Values, ", "))
+			continue
+		}
+		for _, value := range field.Values {
+			if standard {
+				parts = append(parts, decodeOption(o.Universe, o.Code, value))
+			} else {
+				parts = append(parts, "0x"+strings.ToUpper(hex.EncodeToString(mustHex(value))))
+			}
+		}
+	}
+	for _, sub := range o.Options {
+		parts = append(parts, fmt.Sprintf("[%d: %s]", sub.Code, sub.value(false)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseHex reads hex data as Stork and Kea write it, e.g. 0a:00:00:01,
+// 0x0a000001 or "0a 00 00 01".
+func parseHex(text string) ([]byte, error) {
+	text = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(text)), "0x")
+	text = strings.NewReplacer(":", "", " ", "", "-", "").Replace(text)
+	return hex.DecodeString(text)
+}
+
+// decodeOption renders hex option data according to the dictionary, falling
+// back to the hex itself.
+func decodeOption(universe int, code int, value string) string {
+	def, ok := optionDefinition(universe, code)
+	if !ok || def.kind == optBinary {
+		return "0x" + strings.ToUpper(hex.EncodeToString(mustHex(value)))
+	}
+
+	data, err := parseHex(value)
+	if err != nil {
+		return value
+	}
+	decoded, ok := decodeData(def.kind, data)
+	if !ok {
+		return "0x" + strings.ToUpper(hex.EncodeToString(data))
+	}
+	return decoded
+}
+
+// mustHex returns the bytes of hex data, or the text itself if it isn't hex.
+func mustHex(value string) []byte {
+	data, err := parseHex(value)
+	if err != nil {
+		return []byte(value)
+	}
+	return data
+}
+
+// decodeData decodes option data of a known type, reporting false if the
+// data doesn't fit it.
+func decodeData(kind int, data []byte) (string, bool) {
+	switch kind {
+	case optString:
+		return strconv.Quote(strings.TrimRight(string(data), "\x00")), true
+	case optIPv4:
+		if len(data) != 4 {
+			return "", false
+		}
+		return net.IP(data).String(), true
+	case optIPv4List, optIPv6List:
+		size := 4
+		if kind == optIPv6List {
+			size = 16
+		}
+		if len(data) == 0 || len(data)%size != 0 {
+			return "", false
+		}
+		var addrs []string
+		for i := 0; i < len(data); i += size {
+			addrs = append(addrs, net.IP(data[i:i+size]).String())
+		}
+		return strings.Join(addrs, ", "), true
+	case optUint8, optBool:
+		if len(data) != 1 {
+			return "", false
+		}
+		if kind == optBool {
+			return strconv.FormatBool(data[0] != 0), true
+		}
+		return strconv.Itoa(int(data[0])), true
+	case optUint16:
+		if len(data) != 2 {
+			return "", false
+		}
+		return strconv.Itoa(int(binary.BigEndian.Uint16(data))), true
+	case optUint16List:
+		if len(data) == 0 || len(data)%2 != 0 {
+			return "", false
+		}
+		var values []string
+		for i := 0; i < len(data); i += 2 {
+			values = append(values, strconv.Itoa(int(binary.BigEndian.Uint16(data[i:]))))
+		}
+		return strings.Join(values, ", "), true
+	case optUint32, optInt32:
+		if len(data) != 4 {
+			return "", false
+		}
+		if kind == optInt32 {
+			return strconv.Itoa(int(int32(binary.BigEndian.Uint32(data)))), true
+		}
+		return strconv.FormatUint(uint64(binary.BigEndian.Uint32(data)), 10), true
+	case optFqdn, optFqdnList:
+		names, ok := decodeNames(data)
+		return strings.Join(names, ", "), ok
+	case optClasslessRoutes:
+		return decodeClasslessRoutes(data)
+	}
+	return "", false
+}
+
+// decodeNames decodes uncompressed DNS wire format names (RFC 1035).
+func decodeNames(data []byte) ([]string, bool) {
+	var names, labels []string
+	for i := 0; i < len(data); {
+		length := int(data[i])
+		i++
+		if length == 0 {
+			names = append(names, strings.Join(labels, ".")+".")
+			labels = nil
+			continue
+		}
+		if length > 63 || i+length > len(data) {
+			return nil, false
+		}
+		labels = append(labels, string(data[i:i+length]))
+		i += length
+	}
+	if len(labels) > 0 || len(names) == 0 {
+		return nil, false
+	}
+	return names, true
+}
+
+// decodeClasslessRoutes decodes option 121 (RFC 3442): a prefix length, the
+// significant octets of the destination, then the router.
+func decodeClasslessRoutes(data []byte) (string, bool) {
+	var routes []string
+	for i := 0; i < len(data); {
+		bits := int(data[i])
+		octets := (bits + 7) / 8
+		if bits > 32 || i+1+octets+4 > len(data) {
+			return "", false
+		}
+		dest := make(net.IP, 4)
+		copy(dest, data[i+1:i+1+octets])
+		router := net.IP(data[i+1+octets : i+1+octets+4])
+		routes = append(routes, fmt.Sprintf("%s/%d via %s", dest, bits, router))
+		i += 1 + octets + 4
+	}
+	return strings.Join(routes, ", "), len(routes) > 0
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseHex(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "0a:00:00:01", want: "\x0a\x00\x00\x01"},
+		{in: "0x0A000001", want: "\x0a\x00\x00\x01"},
+		{in: " 0a 00 00 01 ", want: "\x0a\x00\x00\x01"},
+		{in: "0a-00", want: "\x0a\x00"},
+		{in: "0a0", err: true},
+		{in: "zz", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			got, err := parseHex(test.in)
+			if (err != nil) != test.err || (err == nil && string(got) != test.want) {
+				t.Errorf("got %q, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestDecodeOption(t *testing.T) {
+	tests := []struct {
+		name     string
+		universe int
+		code     int
+		value    string
+		want     string
+	}{
+		{name: "subnet mask", universe: 4, code: 1, value: "ff:ff:ff:00", want: "255.255.255.0"},
+		{name: "time offset", universe: 4, code: 2, value: "ff:ff:f1:f0", want: "-3600"},
+		{name: "routers", universe: 4, code: 3, value: "0a000001 0a000002", want: "10.0.0.1, 10.0.0.2"},
+		{name: "host name", universe: 4, code: 12, value: "0x7072696e74657200", want: `"printer"`},
+		{name: "ip forwarding", universe: 4, code: 19, value: "01", want: "true"},
+		{name: "default ttl", universe: 4, code: 23, value: "40", want: "64"},
+		{name: "classless routes", universe: 4, code: 121, value: "18:0a:01:02:0a:00:00:01:00:0a:00:00:fe", want: "10.1.2.0/24 via 10.0.0.1, 0.0.0.0/0 via 10.0.0.254"},
+		{name: "bad routes", universe: 4, code: 121, value: "21:0a", want: "0x210A"},
+		{name: "wrong length", universe: 4, code: 1, value: "ff:ff:ff", want: "0xFFFFFF"},
+		{name: "vivso stays hex", universe: 4, code: 125, value: "00:00:0d:e9", want: "0x00000DE9"},
+		{name: "unknown", universe: 4, code: 224, value: "01:02", want: "0x0102"},
+		{name: "not hex", universe: 4, code: 224, value: "abc", want: "0x616263"},
+		{name: "v6 dns servers", universe: 6, code: 23, value: "20010db8000000000000000000000001", want: "2001:db8::1"},
+		{name: "v6 domain search", universe: 6, code: 24, value: "076578616d706c6503636f6d00036c616200", want: "example.com., lab."},
+		{name: "v6 bad name", universe: 6, code: 24, value: "076578616d70", want: "0x076578616D70"},
+		{name: "v6 client arch", universe: 6, code: 61, value: "00:07:00:09", want: "7, 9"},
+		{name: "v6 refresh time", universe: 6, code: 32, value: "00:01:51:80", want: "86400"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := decodeOption(test.universe, test.code, test.value); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestKeaOption(t *testing.T) {
+	tests := []struct {
+		name   string
+		option string
+		want   [2]string
+	}{
+		{
+			name:   "typed fields",
+			option: `{"code":6,"universe":4,"fields":[{"fieldType":"ipv4-address","values":["10.0.0.2","10.0.0.3"]}]}`,
+			want:   [2]string{"domain-name-servers", "10.0.0.2, 10.0.0.3"},
+		},
+		{
+			name:   "hex field",
+			option: `{"code":6,"universe":4,"fields":[{"fieldType":"hex-bytes","values":["0a:00:00:02"]}]}`,
+			want:   [2]string{"domain-name-servers", "10.0.0.2"},
+		},
+		{
+			name:   "suboptions",
+			option: `{"code":43,"universe":4,"fields":[],"options":[{"code":1,"universe":4,"fields":[{"fieldType":"binary","values":["ff:ff:ff:00"]}]},{"code":2,"universe":4,"fields":[{"fieldType":"string","values":["pxe"]}]}]}`,
+			want:   [2]string{"vendor-encapsulated-options", "[1: 0xFFFFFF00], [2: pxe]"},
+		},
+		{
+			name:   "unknown code",
+			option: `{"code":224,"universe":4,"fields":[{"fieldType":"hex-bytes","values":["01:02"]}]}`,
+			want:   [2]string{"option-224", "0x0102"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			option := KeaOption{}
+			if err := json.Unmarshal([]byte(test.option), &option); err != nil {
+				t.Fatal(err)
+			}
+			if got := [2]string{option.Name(), option.Value()}; got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	_ "embed"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:generate go run oui_generate.go
+
+// ouiData maps the first three octets of MAC addresses to vendors, one
+// "AABBCC<tab>Vendor" line per assignment.
+//
+//go:embed oui.txt
+var ouiData string
+
+var (
+	ouiOnce    sync.Once
+	ouiVendors map[string]string
+)
+
+type OuiCmd struct {
+	MACs []string `kong:"arg='',name='mac',help='e.g. 78:12:b6:d9:ce:58'"`
+}
+
+// ouiPrefix returns the OUI of a MAC address as six upper case hex digits.
+func ouiPrefix(mac string) (string, bool) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) < 3 {
+		return "", false
+	}
+	return fmt.Sprintf("%02X%02X%02X", hw[0], hw[1], hw[2]), true
+}
+
+// macVendor returns the vendor a MAC address is assigned to, from the
+// embedded OUI database, or "" if it's unknown.
+func macVendor(mac string) string {
+	ouiOnce.Do(func() {
+		ouiVendors = map[string]string{}
+		for _, line := range strings.Split(ouiData, "\n") {
+			prefix, vendor, ok := strings.Cut(line, "\t")
+			if ok && !strings.HasPrefix(prefix, "#") {
+				ouiVendors[prefix] = strings.TrimSpace(vendor)
+			}
+		}
+	})
+
+	prefix, ok := ouiPrefix(mac)
+	if !ok {
+		return ""
+	}
+	if vendor, ok := ouiVendors[prefix]; ok {
+		return vendor
+	}
+	// Locally administered addresses, e.g. of VMs, have no vendor.
+	if first, _ := strconv.ParseUint(prefix[:2], 16, 8); first&0x02 != 0 {
+		return "(locally administered)"
+	}
+	return ""
+}
+
+// matchesVendor reports whether the vendor of a MAC address contains filter,
+// ignoring case. An empty filter matches everything.
+func matchesVendor(mac string, filter string) bool {
+	return filter == "" || strings.Contains(strings.ToLower(macVendor(mac)), strings.ToLower(filter))
+}
+
+func (o *OuiCmd) Run() error {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetBorder(false)
+	table.SetHeader([]string{"Hardware Address (MAC)", "OUI", "Vendor"})
+	for _, mac := range o.MACs {
+		prefix, ok := ouiPrefix(mac)
+		if !ok {
+			return fmt.Errorf("invalid MAC address %s", mac)
+		}
+		vendor := macVendor(mac)
+		if vendor == "" {
+			vendor = "unknown"
+		}
+		table.Append([]string{mac, prefix, vendor})
+	}
+	fmt.Println()
+	table.Render()
+	fmt.Println()
+	return nil
+}
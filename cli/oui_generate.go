@@ -0,0 +1,96 @@
+//go:build ignore
+
+// oui_generate.go rebuilds oui.txt from the IEEE MA-L registry. Run it with
+// `go generate ./cli` or `make oui`, or pass -registry to read a downloaded
+// copy of the registry CSV instead.
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const registryURL = "https://standards-oui.ieee.org/oui/oui.csv"
+
+func main() {
+	registry := flag.String("registry", registryURL, "URL or file of the IEEE MA-L registry CSV")
+	flag.Parse()
+
+	body, err := openRegistry(*registry)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer body.Close()
+
+	// Columns: Registry, Assignment, Organization Name, Organization Address
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	vendors := map[string]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(record) < 3 || record[0] != "MA-L" {
+			continue
+		}
+		name := strings.Join(strings.Fields(record[2]), " ")
+		vendors[strings.ToUpper(record[1])] = name
+	}
+	if len(vendors) == 0 {
+		log.Fatal("no assignments found")
+	}
+
+	prefixes := make([]string, 0, len(vendors))
+	for prefix := range vendors {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	out, err := os.Create("oui.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "# IEEE MA-L assignments from %s\n", *registry)
+	fmt.Fprintf(w, "# Generated %s by oui_generate.go. Do not edit.\n", time.Now().UTC().Format("2006-01-02"))
+	for _, prefix := range prefixes {
+		fmt.Fprintf(w, "%s\t%s\n", prefix, vendors[prefix])
+	}
+	if err = w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err = out.Close(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d assignments to oui.txt", len(prefixes))
+}
+
+// openRegistry opens the registry CSV from a URL or a file.
+func openRegistry(registry string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(registry, "https://") && !strings.HasPrefix(registry, "http://") {
+		return os.Open(registry)
+	}
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Get(registry)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned %d", registry, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
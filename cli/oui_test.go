@@ -0,0 +1,51 @@
+package cli
+
+import "testing"
+
+func TestOuiPrefix(t *testing.T) {
+	tests := []struct {
+		mac  string
+		want string
+		ok   bool
+	}{
+		{mac: "78:12:b6:d9:ce:58", want: "7812B6", ok: true},
+		{mac: "78-12-B6-D9-CE-58", want: "7812B6", ok: true},
+		{mac: "7812.b6d9.ce58", want: "7812B6", ok: true},
+		{mac: "00:00:0c:01:02:03:04:05", want: "00000C", ok: true},
+		{mac: "78:12:b6", ok: false},
+		{mac: "not a mac", ok: false},
+		{mac: "", ok: false},
+	}
+	for _, test := range tests {
+		t.Run(test.mac, func(t *testing.T) {
+			got, ok := ouiPrefix(test.mac)
+			if got != test.want || ok != test.ok {
+				t.Errorf("got %q, %v, want %q, %v", got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func TestMacVendor(t *testing.T) {
+	tests := []struct {
+		mac  string
+		want string
+	}{
+		{mac: "00:00:0c:12:34:56", want: "Cisco Systems, Inc"},
+		{mac: "b8:27:eb:00:00:01", want: "Raspberry Pi Foundation"},
+		{mac: "02:00:00:00:00:01", want: "(locally administered)"},
+		{mac: "0e:12:34:56:78:9a", want: "(locally administered)"},
+		{mac: "invalid", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.mac, func(t *testing.T) {
+			if got := macVendor(test.mac); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if !matchesVendor("00:00:0c:12:34:56", "cisco") || matchesVendor("00:00:0c:12:34:56", "juniper") || !matchesVendor("invalid", "") {
+		t.Error("matchesVendor")
+	}
+}
@@ -0,0 +1,291 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Sub-options of the relay agent information option 82 (RFC 3046).
+const (
+	relayCircuitID = 1
+	relayRemoteID  = 2
+)
+
+// RelayInfo is the relay agent information Kea stores with a DHCPv4 lease
+// when store-extended-info is enabled.
+type RelayInfo struct {
+	CircuitID []byte
+	RemoteID  []byte
+}
+
+// hexID matches relay IDs given as colon separated hex, e.g. 00:04:00:0a.
+var hexID = regexp.MustCompile(`^[0-9a-fA-F]{2}(:[0-9a-fA-F]{2})+$`)
+
+// parseRelayID reads a relay ID from the command line: hex when written as
+// 0x... or colon separated octets, text otherwise.
+func parseRelayID(text string) ([]byte, error) {
+	if strings.HasPrefix(strings.ToLower(text), "0x") || hexID.MatchString(text) {
+		return parseHex(text)
+	}
+	return []byte(text), nil
+}
+
+// parseRelayInfo extracts the relay agent information from a lease's user
+// context. Kea 2.0 stores option 82 as hex under ISC.relay-agent-info; later
+// versions store a map holding it as sub-options.
+func parseRelayInfo(userContext interface{}) (*RelayInfo, bool) {
+	context, _ := userContext.(map[string]interface{})
+	isc, _ := context["ISC"].(map[string]interface{})
+	var options string
+	switch info := isc["relay-agent-info"].(type) {
+	case string:
+		options = info
+	case map[string]interface{}:
+		options, _ = info["sub-options"].(string)
+	}
+	if options == "" {
+		return nil, false
+	}
+
+	data, err := parseHex(options)
+	if err != nil {
+		return nil, false
+	}
+	relay := RelayInfo{}
+	for i := 0; i+2 <= len(data); {
+		code, length := data[i], int(data[i+1])
+		if i+2+length > len(data) {
+			break
+		}
+		switch code {
+		case relayCircuitID:
+			relay.CircuitID = data[i+2 : i+2+length]
+		case relayRemoteID:
+			relay.RemoteID = data[i+2 : i+2+length]
+		}
+		i += 2 + length
+	}
+	return &relay, relay.CircuitID != nil || relay.RemoteID != nil
+}
+
+// printable reports whether an ID is plain ASCII text.
+func printable(id []byte) bool {
+	for _, c := range id {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return len(id) > 0
+}
+
+// colonHex renders bytes as colon separated hex, as Kea does.
+func colonHex(id []byte) string {
+	octets := make([]string, len(id))
+	for i, c := range id {
+		octets[i] = fmt.Sprintf("%02x", c)
+	}
+	return strings.Join(octets, ":")
+}
+
+// Circuit renders the circuit-id: as text when the switch sends text, e.g.
+// "sw12 Gi1/0/17", as VLAN, module and port in the common binary encoding, or
+// as hex.
+func (r *RelayInfo) Circuit() string {
+	id := r.CircuitID
+	switch {
+	case len(id) == 0:
+		return ""
+	case printable(id):
+		return string(id)
+	case len(id) == 6 && id[0] == 0 && id[1] == 4:
+		return fmt.Sprintf("vlan %d, module %d, port %d", binary.BigEndian.Uint16(id[2:]), id[4], id[5])
+	}
+	return colonHex(id)
+}
+
+// Remote renders the remote-id: as text, as the relay's MAC address in the
+// common binary encoding, or as hex.
+func (r *RelayInfo) Remote() string {
+	id := r.RemoteID
+	switch {
+	case len(id) == 0:
+		return ""
+	case printable(id):
+		return string(id)
+	case len(id) == 8 && id[0] == 0 && id[1] == 6:
+		return net.HardwareAddr(id[2:]).String()
+	}
+	return colonHex(id)
+}
+
+// String describes the relay, e.g. "circuit-id Gi1/0/17, remote-id sw12".
+func (r *RelayInfo) String() string {
+	var parts []string
+	if circuit := r.Circuit(); circuit != "" {
+		parts = append(parts, "circuit-id "+circuit)
+	}
+	if remote := r.Remote(); remote != "" {
+		parts = append(parts, "remote-id "+remote)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// relayInfo returns the relay agent information stored with a lease.
+func (l *KeaLease) relayInfo() (*RelayInfo, bool) {
+	var v interface{}
+	if err := json.Unmarshal(l.UserContext, &v); err != nil {
+		return nil, false
+	}
+	return parseRelayInfo(v)
+}
+
+// getLeasesByRemoteID pages through the leases relayed with a remote-id. The
+// command comes with Kea's lease query hook library, which needs extended
+// info tables enabled.
+func getLeasesByRemoteID(app *KeaAppDetail, remoteID []byte) ([]KeaLease, error) {
+	var leases []KeaLease
+	from := "0.0.0.0"
+	for {
+		var out struct {
+			Leases []KeaLease `json:"leases"`
+		}
+		args := map[string]interface{}{"remote-id": colonHex(remoteID), "lower-address": from, "page-size": exportPageSize}
+		err := keaCommand(app, "dhcp4", "lease4-get-by-remote-id", args, &out)
+		if errors.Is(err, errKeaEmpty) {
+			return leases, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		leases = append(leases, out.Leases...)
+		if len(out.Leases) < exportPageSize {
+			return leases, nil
+		}
+		from = out.Leases[len(out.Leases)-1].IpAddress
+	}
+}
+
+// relayLease is a lease found by relay ID on one Kea instance.
+type relayLease struct {
+	instance string
+	lease    KeaLease
+	relay    *RelayInfo
+}
+
+// findRelayLeases returns an instance's leases relayed with the given
+// circuit-id and remote-id, either of which may be nil. Kea has no lookup by
+// circuit-id, so without a remote-id every lease is paged through.
+func findRelayLeases(app *KeaAppDetail, circuitID []byte, remoteID []byte) ([]relayLease, error) {
+	var found []relayLease
+	keep := func(leases []KeaLease) error {
+		for _, lease := range leases {
+			relay, ok := lease.relayInfo()
+			if !ok {
+				continue
+			}
+			if circuitID != nil && !bytes.Equal(relay.CircuitID, circuitID) {
+				continue
+			}
+			if remoteID != nil && !bytes.Equal(relay.RemoteID, remoteID) {
+				continue
+			}
+			found = append(found, relayLease{instance: app.Name, lease: lease, relay: relay})
+		}
+		return nil
+	}
+
+	if remoteID != nil {
+		leases, err := getLeasesByRemoteID(app, remoteID)
+		if err != nil {
+			return nil, err
+		}
+		err = keep(leases)
+		return found, err
+	}
+	err := getLeasePages(app, "dhcp4", keep)
+	return found, err
+}
+
+// relaySearch lists the leases of an instance, or every instance of a region,
+// that came in through a relay with the given circuit-id or remote-id.
+func (s *SearchCmd) relaySearch() error {
+	if s.Instance == "" {
+		return errors.New("searching by --circuit-id or --remote-id needs --instance")
+	}
+
+	var circuitID, remoteID []byte
+	var err error
+	if s.CircuitID != "" {
+		if circuitID, err = parseRelayID(s.CircuitID); err != nil {
+			return fmt.Errorf("invalid circuit-id %s: %w", s.CircuitID, err)
+		}
+	}
+	if s.RemoteID != "" {
+		if remoteID, err = parseRelayID(s.RemoteID); err != nil {
+			return fmt.Errorf("invalid remote-id %s: %w", s.RemoteID, err)
+		}
+	}
+
+	envName, envURL, err := instanceEnvironment(s.Instance)
+	if err != nil {
+		return err
+	}
+	jar, err := storkAuth(envURL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", envName, err)
+	}
+	instances, err := selectInstances(s.Instance, envURL, jar)
+	if err != nil {
+		return fmt.Errorf("%s: %w", envName, err)
+	}
+
+	var found []relayLease
+	for _, instance := range instances {
+		app, err := getApp(instance, envURL, jar)
+		if err == nil {
+			var leases []relayLease
+			leases, err = findRelayLeases(app, circuitID, remoteID)
+			found = append(found, leases...)
+		}
+		if err != nil {
+			fmt.Printf("%s: %s: %s\n", envName, instance, err.Error())
+		}
+	}
+
+	if len(found) == 0 {
+		fmt.Printf("%s:\nNo leases found for this relay\n\n", envName)
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetBorder(false)
+	table.SetHeader([]string{"Kea Instance", "Hostname", "Hardware Address (MAC)", "Vendor", "IP Address", "Subnet ID", "Circuit ID", "Remote ID", "Expires"})
+	for _, f := range found {
+		table.Append([]string{
+			f.instance,
+			f.lease.Hostname,
+			f.lease.HwAddress,
+			macVendor(f.lease.HwAddress),
+			f.lease.IpAddress,
+			strconv.Itoa(f.lease.SubnetID),
+			f.relay.Circuit(),
+			f.relay.Remote(),
+			formatExpiry(f.lease.Cltt, f.lease.ValidLft),
+		})
+	}
+	fmt.Printf("\n%s:\n", envName)
+	table.Render()
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseRelayID(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "0x0004000a", want: "\x00\x04\x00\x0a"},
+		{in: "0X0A", want: "\x0a"},
+		{in: "00:04:00:0a", want: "\x00\x04\x00\x0a"},
+		{in: "sw12 Gi1/0/17", want: "sw12 Gi1/0/17"},
+		{in: "0a", want: "0a"},
+		{in: "ab:cd:ef:g0", want: "ab:cd:ef:g0"},
+		{in: "0xzz", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			got, err := parseRelayID(test.in)
+			if (err != nil) != test.err || (err == nil && string(got) != test.want) {
+				t.Errorf("got %q, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestParseRelayInfo(t *testing.T) {
+	tests := []struct {
+		name        string
+		userContext string
+		circuit     string
+		remote      string
+		found       bool
+	}{
+		{
+			name:        "kea 2.0 hex",
+			userContext: `{"ISC": {"relay-agent-info": "0x0103676931020473773132"}}`,
+			circuit:     "gi1", remote: "sw12", found: true,
+		},
+		{
+			name:        "sub-options map",
+			userContext: `{"ISC": {"relay-agent-info": {"sub-options": "0x0103676931020473773132"}}}`,
+			circuit:     "gi1", remote: "sw12", found: true,
+		},
+		{
+			name:        "circuit-id only",
+			userContext: `{"ISC": {"relay-agent-info": "01:03:67:69:31"}}`,
+			circuit:     "gi1", found: true,
+		},
+		{
+			name:        "unknown sub-option skipped",
+			userContext: `{"ISC": {"relay-agent-info": "0x05020000020473773132"}}`,
+			remote:      "sw12", found: true,
+		},
+		{
+			name:        "truncated sub-option",
+			userContext: `{"ISC": {"relay-agent-info": "0x01036769310208737731"}}`,
+			circuit:     "gi1", found: true,
+		},
+		{
+			name:        "no relay sub-options",
+			userContext: `{"ISC": {"relay-agent-info": "0x05020000"}}`,
+		},
+		{
+			name:        "not hex",
+			userContext: `{"ISC": {"relay-agent-info": "gi1"}}`,
+		},
+		{
+			name:        "no relay info",
+			userContext: `{"ISC": {}}`,
+		},
+		{
+			name:        "no user context",
+			userContext: `null`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v interface{}
+			if err := json.Unmarshal([]byte(test.userContext), &v); err != nil {
+				t.Fatal(err)
+			}
+			relay, found := parseRelayInfo(v)
+			if found != test.found {
+				t.Fatalf("found = %t, want %t", found, test.found)
+			}
+			if !found {
+				return
+			}
+			if got := relay.Circuit(); got != test.circuit {
+				t.Errorf("circuit-id %q, want %q", got, test.circuit)
+			}
+			if got := relay.Remote(); got != test.remote {
+				t.Errorf("remote-id %q, want %q", got, test.remote)
+			}
+		})
+	}
+}
+
+func TestRelayInfoString(t *testing.T) {
+	tests := []struct {
+		name  string
+		relay RelayInfo
+		want  string
+	}{
+		{
+			name:  "text",
+			relay: RelayInfo{CircuitID: []byte("Gi1/0/17"), RemoteID: []byte("sw12")},
+			want:  "circuit-id Gi1/0/17, remote-id sw12",
+		},
+		{
+			name:  "vlan module port",
+			relay: RelayInfo{CircuitID: []byte{0x00, 0x04, 0x00, 0x0a, 0x01, 0x11}},
+			want:  "circuit-id vlan 10, module 1, port 17",
+		},
+		{
+			name:  "relay mac",
+			relay: RelayInfo{RemoteID: []byte{0x00, 0x06, 0x00, 0x1b, 0x21, 0x3a, 0x4c, 0x5d}},
+			want:  "remote-id 00:1b:21:3a:4c:5d",
+		},
+		{
+			name:  "other binary",
+			relay: RelayInfo{CircuitID: []byte{0x00, 0x04, 0x00, 0x0a}, RemoteID: []byte{0x01, 0xff}},
+			want:  "circuit-id 00:04:00:0a, remote-id 01:ff",
+		},
+		{
+			name:  "empty",
+			relay: RelayInfo{},
+			want:  "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.relay.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLeaseRelayInfo(t *testing.T) {
+	lease := KeaLease{UserContext: json.RawMessage(`{"ISC": {"relay-agent-info": "0x0103676931"}}`)}
+	relay, found := lease.relayInfo()
+	if !found || relay.Circuit() != "gi1" {
+		t.Errorf("got %v, %t", relay, found)
+	}
+
+	lease = KeaLease{}
+	if _, found := lease.relayInfo(); found {
+		t.Error("lease without user context has relay info")
+	}
+}
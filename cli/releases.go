@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+const (
+	// ReleaseIndexURL lists every published release and the channel it was
+	// published to. Each release lives under ArtifactsBaseURL/<version>/.
+	ReleaseIndexURL = ArtifactsBaseURL + "index.json"
+)
+
+type ReleaseIndex struct {
+	Releases []Release `json:"releases"`
+}
+
+type Release struct {
+	Version string         `json:"version"`
+	Tag     string         `json:"tag"`
+	Channel string         `json:"channel"`
+	Date    time.Time      `json:"date"`
+	Sizes   map[string]int `json:"sizes"`
+}
+
+// BaseURL returns the artifacts directory holding this release.
+func (r *Release) BaseURL() string {
+	return ArtifactsBaseURL + r.Version + "/"
+}
+
+// Matches reports whether ref names this release, either by tag or by a
+// (possibly abbreviated) commit hash.
+func (r *Release) Matches(ref string) bool {
+	if ref == "" {
+		return false
+	}
+	if r.Tag != "" && r.Tag == ref {
+		return true
+	}
+	return len(ref) >= 7 && strings.HasPrefix(r.Version, ref)
+}
+
+func fetchReleaseIndex() (*ReleaseIndex, error) {
+	indexBytes, err := httpGET(ReleaseIndexURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch release index: %w", err)
+	}
+
+	var index ReleaseIndex
+	err = json.Unmarshal(indexBytes, &index)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse release index: %w", err)
+	}
+	return &index, nil
+}
+
+// findRelease looks up a single release by tag or commit.
+func findRelease(ref string) (*Release, error) {
+	index, err := fetchReleaseIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Release
+	for _, release := range index.Releases {
+		if release.Matches(ref) {
+			found = append(found, release)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no published release matches %q", ref)
+	case 1:
+		return &found[0], nil
+	default:
+		return nil, fmt.Errorf("%q matches %d releases; use a longer commit hash", ref, len(found))
+	}
+}
+
+// ListReleases prints the published releases for each channel, newest first.
+func ListReleases() error {
+	index, err := fetchReleaseIndex()
+	if err != nil {
+		return err
+	}
+
+	filename := BinaryName
+	if runtime.GOOS == "windows" {
+		filename += ".exe"
+	}
+	path := fmt.Sprintf("%s/%s/%s", runtime.GOOS, runtime.GOARCH, filename)
+
+	channels := map[string][]Release{}
+	for _, release := range index.Releases {
+		channels[release.Channel] = append(channels[release.Channel], release)
+	}
+
+	names := make([]string, 0, len(channels))
+	for name := range channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		releases := channels[name]
+		sort.Slice(releases, func(i, j int) bool {
+			return releases[i].Date.After(releases[j].Date)
+		})
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetAutoWrapText(false)
+		table.SetBorder(false)
+		table.SetHeader([]string{"Version", "Tag", "Date", "Size (" + path + ")"})
+		for _, release := range releases {
+			size := "-"
+			if s, ok := release.Sizes[path]; ok {
+				size = fmt.Sprintf("%.1f MiB", float64(s)/(1<<20))
+			}
+			table.Append([]string{
+				release.Version,
+				release.Tag,
+				release.Date.Local().Format("2006-01-02 15:04"),
+				size,
+			})
+		}
+		fmt.Printf("\n%s:\n", name)
+		table.Render()
+	}
+	fmt.Println()
+	return nil
+}
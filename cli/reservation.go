@@ -1,137 +1,213 @@
 package cli
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/olekukonko/tablewriter"
-	"io"
-	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"os"
-	"regexp"
 	"strconv"
 )
 
 type ResCmd struct {
-	ResTerm string `kong:"arg='',name='IP address/subnet or Region',help='e.g. 10.4.2.5/27, NYC3'"`
+	ResTerm string `kong:"arg='',name='IP address/subnet or Region',completion='instances',help='e.g. 10.4.2.5, 10.4.2.0/27, NYC3'"`
+	Vendor  string `kong:"optional,help='Only show reservations for MAC addresses of this vendor, e.g. Super Micro.'"`
+	Detail  bool   `kong:"optional,short='d',help='Show the DHCP options and boot fields of each reservation.'"`
 }
 
 type KeaRes struct {
-	Total int `json:"total"`
-	Items []struct {
-		AddressReservations []struct {
-			Address string `json:"address"`
-		} `json:"addressReservations"`
-		HostIdentifiers []struct {
-			IdHexValue string `json:"idHexValue"`
-		} `json:"hostIdentifiers"`
-		LocalHosts []struct {
-			AppName string `json:"appName"`
-		} `json:"localHosts"`
-	} `json:"items"`
+	Total int       `json:"total"`
+	Items []KeaHost `json:"items"`
 }
 
-func (r *ResCmd) Run() error {
-
-	// Basic input validation
-	// If searchTerm appears to be a valid IP or region
-	ipaddr, _ := regexp.Compile("(25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)(\\.(25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)){3}")
-	region, _ := regexp.Compile("(\\w{3}\\d+)")
-	searchTerm := r.ResTerm
+type KeaHost struct {
+	ID                  int    `json:"id"`
+	SubnetID            int    `json:"subnetId"`
+	SubnetPrefix        string `json:"subnetPrefix"`
+	Hostname            string `json:"hostname"`
+	AddressReservations []struct {
+		Address string `json:"address"`
+	} `json:"addressReservations"`
+	HostIdentifiers []struct {
+		IdType     string `json:"idType"`
+		IdHexValue string `json:"idHexValue"`
+	} `json:"hostIdentifiers"`
+	LocalHosts []struct {
+		AppID          int         `json:"appId"`
+		AppName        string      `json:"appName"`
+		DataSource     string      `json:"dataSource"`
+		Hostname       string      `json:"hostname"`
+		NextServer     string      `json:"nextServer"`
+		ServerHostname string      `json:"serverHostname"`
+		BootFileName   string      `json:"bootFileName"`
+		Options        []KeaOption `json:"options"`
+	} `json:"localHosts"`
+}
 
-	if !(ipaddr.MatchString(searchTerm) || region.MatchString(searchTerm)) {
-		return errors.New("Invalid input!")
+// optionRows returns a reservation's boot fields and options as name/value
+// pairs, as configured on its first Kea instance. HA partners carry the same.
+func (h *KeaHost) optionRows() [][]string {
+	if len(h.LocalHosts) == 0 {
+		return nil
 	}
+	local := h.LocalHosts[0]
 
-	var envName = "Production"
-	var envURL = environments[envName]
-
-	if region.MatchString(searchTerm) {
-		if isStage2(searchTerm) {
-			envName = "Stage2"
-			envURL = environments[envName]
-		}
+	var rows [][]string
+	if local.NextServer != "" {
+		rows = append(rows, []string{"next-server (siaddr)", local.NextServer})
 	}
+	if local.ServerHostname != "" {
+		rows = append(rows, []string{"server-hostname (sname)", strconv.Quote(local.ServerHostname)})
+	}
+	if local.BootFileName != "" {
+		rows = append(rows, []string{"boot-file-name (file)", strconv.Quote(local.BootFileName)})
+	}
+	for i := range local.Options {
+		option := &local.Options[i]
+		rows = append(rows, []string{fmt.Sprintf("%s (%d)", option.Name(), option.Code), option.Value()})
+	}
+	return rows
+}
 
-	storkURL, err := url.Parse(envURL)
+// getReservations fetches every host reservation matching query, e.g. an
+// appId or subnetId.
+func getReservations(query url.Values, envURL *url.URL, jar *cookiejar.Jar) (*KeaRes, error) {
+	items, err := getAllPages[KeaHost]("/api/hosts", query, envURL, jar)
 	if err != nil {
-		fmt.Printf("Error parsing environment URLs: %v", err.Error())
-		return err
+		return nil, fmt.Errorf("error searching reservations: %w", err)
 	}
+	return &KeaRes{Total: len(items), Items: items}, nil
+}
 
-	jar, err := storkAuth(storkURL)
+// tableRow returns the instance, identifier, vendor and address columns of a
+// reservation, with "-" for what it lacks, e.g. a host reserving only
+// options or a prefix.
+func (h *KeaHost) tableRow() []string {
+	row := []string{"-", "-", "-", "-"}
+	if len(h.LocalHosts) > 0 {
+		row[0] = h.LocalHosts[0].AppName
+	}
+	if len(h.HostIdentifiers) > 0 {
+		row[1] = h.HostIdentifiers[0].IdHexValue
+	}
+	if mac := h.HwAddress(); mac != "" {
+		row[2] = macVendor(mac)
+	}
+	if len(h.AddressReservations) > 0 {
+		row[3] = h.AddressReservations[0].Address
+	}
+	return row
+}
+
+func (r *ResCmd) Run() error {
+	searchTerm := r.ResTerm
+
+	envs, err := storkLogin()
 	if err != nil {
 		return err
 	}
 
-	client := &http.Client{Jar: jar}
+	var env *stork
+	// filter sets the subnetId or appId of the search, looking the ID up
+	// again should the cached one turn out stale.
+	var filter func(query url.Values) error
 
-	storkURL.Path = "/api/hosts"
-	queryValues := storkURL.Query()
-	queryValues.Del("text")
-	queryValues.Set("limit", "100")
-
-	switch {
-	case ipaddr.MatchString(searchTerm):
-		subnetURL, _ := url.Parse(envURL)
-		subnetID, err := getSubnetID(searchTerm, subnetURL, jar)
+	if target, err := parseTarget(searchTerm); err == nil {
+		// An address or CIDR: the most specific subnet containing it, in
+		// whichever environment serves it.
+		match, err := findSubnet(target, envs)
 		if err != nil {
-			fmt.Printf("%s: %s: %s", envName, searchTerm, err.Error())
+			fmt.Printf("%s: %s\n", searchTerm, err.Error())
 			return err
 		}
-		queryValues.Set("subnetId", strconv.Itoa(subnetID))
-	case region.MatchString(searchTerm):
-		appURL, _ := url.Parse(envURL)
-		appID, err := getAppID(searchTerm, appURL, jar)
-		if err != nil {
-			fmt.Printf("%s: %s: %s", envName, searchTerm, err.Error())
+		env = &match.Env
+		filter = func(query url.Values) error {
+			id, err := subnetID(match.Prefix, *env)
+			query.Set("subnetId", strconv.Itoa(id))
 			return err
 		}
-		queryValues.Set("appId", strconv.Itoa(appID))
-	}
-	storkURL.RawQuery = queryValues.Encode()
-
-	resp, err := client.Get(storkURL.String())
-	if err != nil {
-		return err
-	}
 
-	if resp.StatusCode != 200 {
-		fmt.Printf("Error: Stork returned %d on search!", resp.StatusCode)
+		fmt.Printf("\n%s: %s is in subnet %s", env.name, searchTerm, match.Subnet.Subnet)
+		switch {
+		case match.Pool != "":
+			fmt.Printf(", pool %s", match.Pool)
+		case target.IsSingleIP():
+			fmt.Print(", outside the dynamic pools")
+		}
+		fmt.Println()
+	} else {
+		// A Kea instance, in whichever environment knows it.
+		for i := range envs {
+			if _, err := getAppID(searchTerm, envs[i].url, envs[i].jar); err == nil {
+				env = &envs[i]
+				break
+			}
+		}
+		if env == nil {
+			return fmt.Errorf("%s is not an IP address, CIDR or Kea instance", searchTerm)
+		}
+		filter = func(query url.Values) error {
+			appID, err := getAppID(searchTerm, env.url, env.jar)
+			query.Set("appId", strconv.Itoa(appID))
+			return err
+		}
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	envName, storkURL, jar := env.name, env.url, env.jar
+	k := &KeaRes{}
+	_, err = retryStale(storkURL, func() ([]KeaHost, error) {
+		query := url.Values{}
+		if err := filter(query); err != nil {
+			return nil, err
+		}
+		res, err := getReservations(query, storkURL, jar)
+		if err != nil {
+			return nil, err
+		}
+		k = res
+		return k.Items, nil
+	})
 	if err != nil {
+		fmt.Printf("%s: %s: %s", envName, searchTerm, err.Error())
 		return err
 	}
 
-	defer resp.Body.Close()
-
-	if len(body) == 0 {
-		fmt.Printf("%s: unexpected output from Stork server", envName)
-	}
-
-	k := KeaRes{}
-	jsonErr := json.Unmarshal(body, &k)
-	if jsonErr != nil {
-		return jsonErr
-	}
-
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetAutoWrapText(false)
 	table.SetBorder(false)
-	table.SetHeader([]string{"Kea Instance", "Hardware Address (MAC)", "IP Address"})
+	if r.Detail {
+		table.SetHeader([]string{"Kea Instance", "Hardware Address (MAC)", "Vendor", "IP Address", "Option", "Value"})
+		table.SetAutoMergeCellsByColumnIndex([]int{0, 1, 2, 3})
+		table.SetRowLine(true)
+	} else {
+		table.SetHeader([]string{"Kea Instance", "Hardware Address (MAC)", "Vendor", "IP Address"})
+	}
+	count := 0
 	for reservation := range k.Items {
+		if !matchesVendor(k.Items[reservation].HwAddress(), r.Vendor) {
+			continue
+		}
+
 		// Build the table structure for each daemon entry
-		data := []string{
-			k.Items[reservation].LocalHosts[0].AppName,
-			k.Items[reservation].HostIdentifiers[0].IdHexValue,
-			k.Items[reservation].AddressReservations[0].Address,
+		data := k.Items[reservation].tableRow()
+		if r.Detail {
+			rows := k.Items[reservation].optionRows()
+			if len(rows) == 0 {
+				rows = [][]string{{"", "(no options)"}}
+			}
+			for _, row := range rows {
+				table.Append(append(append([]string{}, data...), row...))
+			}
+		} else {
+			table.Append(data)
 		}
-		table.Append(data)
+		count++
+	}
+	if r.Vendor != "" {
+		fmt.Printf("\n%s: (%d of %d reserved addresses from %s)\n", envName, count, k.Total, r.Vendor)
+	} else {
+		fmt.Printf("\n%s: (%d reserved addresses)\n", envName, k.Total)
 	}
-	fmt.Printf("\n%s: (%d reserved addresses)\n", envName, k.Total)
 	table.Render()
 	fmt.Println()
 	return nil
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGetReservationsPages(t *testing.T) {
+	const total = 250
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		var items []string
+		for id := start; id < start+limit && id < total; id++ {
+			items = append(items, fmt.Sprintf(`{"id":%d}`, id))
+		}
+		fmt.Fprintf(w, `{"total":%d,"items":[%s]}`, total, strings.Join(items, ","))
+	}))
+	defer server.Close()
+
+	envURL, _ := url.Parse(server.URL)
+	query := url.Values{}
+	query.Set("subnetId", "7")
+	k, err := getReservations(query, envURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Total != total || len(k.Items) != total || k.Items[total-1].ID != total-1 {
+		t.Errorf("got %d of %d reservations", len(k.Items), k.Total)
+	}
+	want := []string{
+		"limit=100&start=0&subnetId=7",
+		"limit=100&start=100&subnetId=7",
+		"limit=100&start=200&subnetId=7",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("got queries %q, want %q", queries, want)
+	}
+}
+
+func TestKeaHostTableRow(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want []string
+	}{
+		{
+			name: "complete",
+			host: `{"addressReservations":[{"address":"10.0.0.5"}],"hostIdentifiers":[{"idType":"hw-address","idHexValue":"02:00:00:00:00:01"}],"localHosts":[{"appName":"NYC3"}]}`,
+			want: []string{"NYC3", "02:00:00:00:00:01", "(locally administered)", "10.0.0.5"},
+		},
+		{
+			name: "no address",
+			host: `{"hostIdentifiers":[{"idType":"client-id","idHexValue":"01:02"}],"localHosts":[{"appName":"NYC3"}]}`,
+			want: []string{"NYC3", "01:02", "-", "-"},
+		},
+		{name: "empty", host: `{}`, want: []string{"-", "-", "-", "-"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			host := testHost(t, test.host)
+			if got := host.tableRow(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -1,36 +1,94 @@
 package cli
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/olekukonko/tablewriter"
-	"io"
 	"log"
 	"net"
-	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"os"
 	"strconv"
+	"time"
 )
 
 type SearchCmd struct {
-	LeaseSearch string `kong:"arg='',name='MAC or IP address',help='e.g. 78:12:b6:d9:ce:58 or 10.30.2.4'"`
+	LeaseSearch string `kong:"arg='',optional,name='MAC or IP address',help='e.g. 78:12:b6:d9:ce:58 or 10.30.2.4'"`
+	CircuitID   string `kong:"optional,name='circuit-id',help='Find the leases relayed with this option 82 circuit-id, as text or hex (0x... or 00:04:...).'"`
+	RemoteID    string `kong:"optional,name='remote-id',help='Find the leases relayed with this option 82 remote-id, as text or hex.'"`
+	Instance    string `kong:"optional,short='i',completion='regions',help='Kea instance or region to search by circuit-id or remote-id, e.g. NYC3.'"`
 }
 
 type Lease struct {
-	Total int `json:"total"`
-	Items []struct {
-		AppName   string `json:"appName"`
-		Hostname  string `json:"hostname"`
-		HwAddress string `json:"hwAddress"`
-		IpAddress string `json:"ipAddress"`
-		SubnetID  int    `json:"subnetId"`
-		State     int    `json:"state"`
-	} `json:"items"`
+	Total int         `json:"total"`
+	Items []LeaseItem `json:"items"`
+}
+
+type LeaseItem struct {
+	AppID         int         `json:"appId"`
+	AppName       string      `json:"appName"`
+	Hostname      string      `json:"hostname"`
+	HwAddress     string      `json:"hwAddress"`
+	IpAddress     string      `json:"ipAddress"`
+	SubnetID      int         `json:"subnetId"`
+	State         int         `json:"state"`
+	Cltt          int64       `json:"cltt"`
+	ValidLifetime int64       `json:"validLifetime"`
+	UserContext   interface{} `json:"userContext"`
+}
+
+// ExpiredUnreclaimed reports whether a lease has expired but Kea hasn't
+// reclaimed it yet, so it still holds the address.
+func (l LeaseItem) ExpiredUnreclaimed() bool {
+	expiry := l.Expiry()
+	return l.State == leaseDefault && l.ValidLifetime > 0 && !expiry.IsZero() && time.Now().After(expiry)
+}
+
+// StateText describes the state of a lease.
+func (l LeaseItem) StateText() string {
+	switch {
+	case l.ExpiredUnreclaimed():
+		return "Expired - not reclaimed"
+	case l.State == leaseDefault:
+		return "Active"
+	case l.State == leaseDeclined:
+		return "Declined - see dhcli leases declined"
+	case l.State == leaseReclaimed:
+		return "Expired - reclaimed"
+	}
+	return "Inactive - check logs"
+}
+
+// Lease states as reported by Kea.
+const (
+	leaseDefault   = 0
+	leaseDeclined  = 1
+	leaseReclaimed = 2
+)
+
+// getLeases searches the leases of every Kea instance in an environment by
+// IP address, MAC address or hostname.
+func getLeases(text string, envURL *url.URL, jar *cookiejar.Jar) (*Lease, error) {
+	query := url.Values{}
+	query.Set("text", text)
+
+	l := Lease{}
+	err := storkGet("/api/leases", query, envURL, jar, &l)
+	if err != nil {
+		return nil, fmt.Errorf("error searching leases: %w", err)
+	}
+	return &l, nil
 }
 
 func (s *SearchCmd) Run() error {
+	if s.CircuitID != "" || s.RemoteID != "" {
+		return s.relaySearch()
+	}
 	searchTerm := s.LeaseSearch
+	if searchTerm == "" {
+		return errors.New("give a MAC or IP address, or --circuit-id/--remote-id with --instance")
+	}
 
 	// Basic input validation
 	// If searchTerm appears to be a valid IP or MAC address we continue
@@ -49,80 +107,56 @@ func (s *SearchCmd) Run() error {
 
 		jar, err := storkAuth(envURL)
 		if err != nil {
-			fmt.Print(err.Error())
-		}
-
-		client := &http.Client{Jar: jar}
-
-		envURL.Path = "/api/leases"
-		queryValues := envURL.Query()
-		queryValues.Set("text", searchTerm)
-		envURL.RawQuery = queryValues.Encode()
-
-		resp, err := client.Get(envURL.String())
-		if err != nil {
-			log.Print(err.Error())
-		}
-
-		if resp.StatusCode != 200 {
-			log.Printf("Error: Stork returned %d on search!", resp.StatusCode)
+			log.Printf("%s: %s", envName, err.Error())
 			continue
 		}
 
-		body, err := io.ReadAll(resp.Body)
+		l, err := getLeases(searchTerm, envURL, jar)
 		if err != nil {
-			log.Fatalln(err)
-		}
-
-		// Manually calling close due to for loop
-		// We aren't checking errors on this, because they don't really impact the outcome
-		_ = resp.Body.Close()
-
-		if len(body) == 0 {
-			log.Printf("Unexpected output from Stork server: %s", err.Error())
-		}
-
-		l := Lease{}
-
-		jsonErr := json.Unmarshal(body, &l)
-		if jsonErr != nil {
-			return jsonErr
+			log.Printf("%s: %s", envName, err.Error())
+			continue
 		}
 
 		switch {
 		case l.Total == 0:
 			fmt.Printf("%s:\nNo results found for: %s\n\n", envName, searchTerm)
 		case l.Total == 1:
-			var state string
-			if l.Items[0].State == 0 {
-				state = "Active"
-			} else {
-				state = "Inactive - check logs"
-			}
-
 			table := tablewriter.NewWriter(os.Stdout)
 			table.SetAutoWrapText(false)
 			table.SetBorder(false)
 			table.SetHeader([]string{"Kea Instance",
 				"Hostname",
 				"Hardware Address (MAC)",
+				"Vendor",
 				"IP Address",
 				"Subnet ID",
-				"Lease State"})
+				"Lease State",
+				"Last Transaction",
+				"Valid Lifetime",
+				"Expires"})
 			for lease := range l.Items {
 				// Build the table structure for each daemon entry
 				data := []string{
 					l.Items[lease].AppName,
 					l.Items[lease].Hostname,
 					l.Items[lease].HwAddress,
+					macVendor(l.Items[lease].HwAddress),
 					l.Items[lease].IpAddress,
 					strconv.Itoa(l.Items[lease].SubnetID),
-					state,
+					l.Items[lease].StateText(),
+					formatTimeRelative(l.Items[lease].LastTransaction()),
+					humanDuration(time.Duration(l.Items[lease].ValidLifetime) * time.Second),
+					formatExpiry(l.Items[lease].Cltt, l.Items[lease].ValidLifetime),
 				}
 				table.Append(data)
 			}
 			fmt.Printf("\n%s:\n", envName)
 			table.Render()
+			for _, lease := range l.Items {
+				if relay, ok := parseRelayInfo(lease.UserContext); ok {
+					fmt.Printf("\nRelayed with %s\n", relay)
+				}
+			}
 			fmt.Print("\n")
 		case l.Total >= 2:
 			fmt.Printf("Ambiguous results returned for: %s\n"+
@@ -133,8 +167,6 @@ func (s *SearchCmd) Run() error {
 		default:
 			log.Printf("%s: unknown error occured searching for: %s", envName, searchTerm)
 		}
-		// Reset the switch var for the next run of the loop as there's a chance we won't have a valid value
-		l.Total = 0
 	}
 	return nil
 }
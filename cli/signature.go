@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	_ "embed"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SignatureSuffix is appended to a manifest URL to locate its detached
+// minisign signature.
+const SignatureSuffix = ".minisig"
+
+// releaseKeys holds the minisign public keys trusted to sign release
+// manifests, one per line. To rotate keys, add the new key here, ship a
+// release signed with the old key, then start signing with the new one and
+// drop the old key in a later release.
+//
+//go:embed release_keys.pub
+var releaseKeys string
+
+// publicKey is a minisign ed25519 public key.
+type publicKey struct {
+	ID  uint64
+	Key ed25519.PublicKey
+}
+
+// signature is a parsed minisign detached signature.
+type signature struct {
+	KeyID           uint64
+	Signature       []byte
+	TrustedComment  string
+	GlobalSignature []byte
+}
+
+// trustedKeys parses the embedded release keys.
+func trustedKeys() ([]publicKey, error) {
+	var keys []publicKey
+	scanner := bufio.NewScanner(strings.NewReader(releaseKeys))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "untrusted comment:") {
+			continue
+		}
+		key, err := parsePublicKey(line)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, scanner.Err()
+}
+
+// parsePublicKey decodes the base64 line of a minisign public key file.
+func parsePublicKey(encoded string) (publicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return publicKey{}, fmt.Errorf("invalid public key encoding: %w", err)
+	}
+	if len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return publicKey{}, errors.New("invalid public key: not a minisign ed25519 key")
+	}
+	return publicKey{
+		ID:  binary.LittleEndian.Uint64(raw[2:10]),
+		Key: ed25519.PublicKey(raw[10:]),
+	}, nil
+}
+
+// parseSignature decodes a minisign signature file. Only legacy ("Ed")
+// signatures over the raw message are supported; sign with `minisign -S -l`.
+func parseSignature(data []byte) (signature, error) {
+	var lines []string
+	for _, line := range strings.Split(string(bytes.TrimSpace(data)), "\n") {
+		lines = append(lines, strings.TrimRight(line, "\r"))
+	}
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "untrusted comment:") {
+		return signature{}, errors.New("malformed signature file")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return signature{}, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if len(raw) != 2+8+ed25519.SignatureSize {
+		return signature{}, errors.New("invalid signature length")
+	}
+	if string(raw[:2]) != "Ed" {
+		return signature{}, fmt.Errorf("unsupported signature algorithm %q", raw[:2])
+	}
+
+	const trustedPrefix = "trusted comment: "
+	if !strings.HasPrefix(lines[2], trustedPrefix) {
+		return signature{}, errors.New("malformed signature file: missing trusted comment")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return signature{}, errors.New("invalid global signature")
+	}
+
+	return signature{
+		KeyID:           binary.LittleEndian.Uint64(raw[2:10]),
+		Signature:       raw[10:],
+		TrustedComment:  strings.TrimPrefix(lines[2], trustedPrefix),
+		GlobalSignature: global,
+	}, nil
+}
+
+// verifyManifest checks that sig is a valid signature of manifest made by one
+// of the embedded release keys.
+func verifyManifest(manifest []byte, sig []byte) error {
+	s, err := parseSignature(sig)
+	if err != nil {
+		return err
+	}
+
+	keys, err := trustedKeys()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errors.New("no trusted release keys are embedded in this binary: it was built without a key in cli/release_keys.pub, so it can't verify releases; install a published release instead")
+	}
+
+	for _, key := range keys {
+		if key.ID != s.KeyID {
+			continue
+		}
+		if !ed25519.Verify(key.Key, manifest, s.Signature) {
+			return errors.New("manifest signature verification failed")
+		}
+		global := append(append([]byte{}, s.Signature...), s.TrustedComment...)
+		if !ed25519.Verify(key.Key, global, s.GlobalSignature) {
+			return errors.New("trusted comment signature verification failed")
+		}
+		return nil
+	}
+	return fmt.Errorf("manifest signed by untrusted key %016X", s.KeyID)
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testKeyID = 0x0123456789ABCDEF
+
+var testPrivateKey = ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+
+// testPublicKey returns a minisign public key file for the test key.
+func testPublicKey(id uint64) string {
+	raw := []byte("Ed")
+	raw = binary.LittleEndian.AppendUint64(raw, id)
+	raw = append(raw, testPrivateKey.Public().(ed25519.PublicKey)...)
+	return "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(raw) + "\n"
+}
+
+// testSignature signs message the way `minisign -S -l` does.
+func testSignature(algorithm string, id uint64, message []byte, comment string) []byte {
+	sig := ed25519.Sign(testPrivateKey, message)
+	raw := []byte(algorithm)
+	raw = binary.LittleEndian.AppendUint64(raw, id)
+	raw = append(raw, sig...)
+	global := ed25519.Sign(testPrivateKey, append(append([]byte{}, sig...), comment...))
+	return []byte(fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(raw), comment, base64.StdEncoding.EncodeToString(global)))
+}
+
+func withReleaseKeys(t *testing.T, keys string) {
+	saved := releaseKeys
+	releaseKeys = keys
+	t.Cleanup(func() { releaseKeys = saved })
+}
+
+func TestParseSignature(t *testing.T) {
+	manifest := []byte(`{"version":"abc"}`)
+	good := testSignature("Ed", testKeyID, manifest, "dhcli abc")
+
+	tests := []struct {
+		name string
+		sig  []byte
+		err  string
+	}{
+		{name: "good", sig: good},
+		{name: "crlf", sig: bytes.ReplaceAll(good, []byte("\n"), []byte("\r\n"))},
+		{name: "prehashed", sig: testSignature("ED", testKeyID, manifest, "dhcli abc"), err: "unsupported signature algorithm"},
+		{name: "missing lines", sig: good[:bytes.LastIndexByte(good[:len(good)-1], '\n')], err: "malformed signature file"},
+		{name: "bad encoding", sig: []byte("untrusted comment: x\n!!!\ntrusted comment: x\nAAAA\n"), err: "invalid signature encoding"},
+		{name: "short", sig: []byte("untrusted comment: x\nRWQ=\ntrusted comment: x\nAAAA\n"), err: "invalid signature length"},
+		{name: "no trusted comment", sig: bytes.Replace(good, []byte("\ntrusted comment: "), []byte("\ncomment: "), 1), err: "missing trusted comment"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := parseSignature(test.sig)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.KeyID != testKeyID || s.TrustedComment != "dhcli abc" {
+				t.Errorf("got key %016X, comment %q", s.KeyID, s.TrustedComment)
+			}
+		})
+	}
+}
+
+func TestVerifyManifest(t *testing.T) {
+	manifest := []byte(`{"version":"abc"}`)
+	good := testSignature("Ed", testKeyID, manifest, "dhcli abc")
+
+	tests := []struct {
+		name     string
+		keys     string
+		manifest []byte
+		sig      []byte
+		err      string
+	}{
+		{name: "good", keys: testPublicKey(testKeyID), manifest: manifest, sig: good},
+		{name: "second key", keys: testPublicKey(1) + testPublicKey(testKeyID), manifest: manifest, sig: good},
+		{name: "tampered manifest", keys: testPublicKey(testKeyID), manifest: []byte(`{"version":"evil"}`), sig: good, err: "manifest signature verification failed"},
+		{name: "tampered comment", keys: testPublicKey(testKeyID), manifest: manifest, sig: bytes.Replace(good, []byte("dhcli abc"), []byte("dhcli xyz"), 1), err: "trusted comment signature verification failed"},
+		{name: "unknown key", keys: testPublicKey(1), manifest: manifest, sig: good, err: "untrusted key 0123456789ABCDEF"},
+		{name: "prehashed", keys: testPublicKey(testKeyID), manifest: manifest, sig: testSignature("ED", testKeyID, manifest, "dhcli abc"), err: "unsupported signature algorithm"},
+		{name: "no keys", keys: "# none\n", manifest: manifest, sig: good, err: "no trusted release keys"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withReleaseKeys(t, test.keys)
+			err := verifyManifest(test.manifest, test.sig)
+			if test.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
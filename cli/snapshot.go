@@ -0,0 +1,345 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type ConfigSnapshotCmd struct {
+	Instance string   `kong:"arg='',name='instance-or-region',completion='regions',help='Kea instance, or a prefix selecting several, e.g. NYC3 or S2R'"`
+	Dir      string   `kong:"optional,short='o',default='.',help='Directory to write the snapshots to.'"`
+	Daemon   []string `kong:"optional,short='d',help='Only save these daemons (dhcp4, dhcp6, d2, ca). Default: all daemons of the instance.'"`
+}
+
+type ConfigDriftCmd struct {
+	Instance string   `kong:"arg='',optional,name='instance-or-region',completion='regions',help='Only check snapshots of this Kea instance, or of instances with this prefix'"`
+	Dir      string   `kong:"optional,short='o',default='.',help='Directory holding the snapshots.'"`
+	Ignore   []string `kong:"optional,short='i',help='Ignore differences under a path pattern (repeatable), as in config diff.'"`
+}
+
+// snapshotDaemons are the daemons a snapshot can hold, as used in snapshot
+// file names.
+var snapshotDaemons = []string{"dhcp4", "dhcp6", "d2", "ca"}
+
+// snapshotFile names the snapshot of one daemon of an instance.
+func snapshotFile(dir string, instance string, daemon string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s.%s.json", instance, daemon))
+}
+
+// parseSnapshotFile returns the instance and daemon of a snapshot file, or
+// false for other files.
+func parseSnapshotFile(path string) (string, string, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), ".json")
+	for _, daemon := range snapshotDaemons {
+		if instance := strings.TrimSuffix(name, "."+daemon); instance != name && instance != "" {
+			return instance, daemon, true
+		}
+	}
+	return "", "", false
+}
+
+// matchesInstance reports whether an instance is selected by a name or prefix,
+// ignoring case. An empty selector matches everything.
+func matchesInstance(instance string, selector string) bool {
+	return strings.HasPrefix(strings.ToLower(instance), strings.ToLower(selector))
+}
+
+// selectInstances returns the Kea instances named, or prefixed, by selector.
+// An exact name only selects that instance.
+func selectInstances(selector string, envURL *url.URL, jar *cookiejar.Jar) ([]string, error) {
+	apps, err := getApps(envURL, jar)
+	if err != nil {
+		return nil, fmt.Errorf("error listing apps: %w", err)
+	}
+
+	var names []string
+	for _, app := range apps.Items {
+		names = append(names, app.AppName)
+	}
+	return matchInstances(names, selector)
+}
+
+// matchInstances picks the instance names selected by a name or prefix.
+func matchInstances(names []string, selector string) ([]string, error) {
+	var instances []string
+	for _, name := range names {
+		if strings.EqualFold(name, selector) {
+			return []string{name}, nil
+		}
+		if matchesInstance(name, selector) {
+			instances = append(instances, name)
+		}
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("no Kea instances match %s", selector)
+	}
+	sort.Strings(instances)
+	return instances, nil
+}
+
+// normalizeConfig renders a configuration for storage: secrets scrubbed and
+// keys sorted, so that snapshots only change when the configuration does.
+func normalizeConfig(v interface{}) ([]byte, error) {
+	out, err := formatConfig(scrubConfig(v), "json")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// readSnapshot reads a stored configuration, keeping numbers as written so
+// that they compare equal to live ones.
+func readSnapshot(path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&v)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// wantDaemon reports whether a daemon was asked for, all daemons being wanted
+// by default.
+func wantDaemon(daemons []string, name string) bool {
+	if len(daemons) == 0 {
+		return true
+	}
+	for _, daemon := range daemons {
+		if strings.EqualFold(daemon, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ConfigSnapshotCmd) Run() error {
+	envName, envURL, err := instanceEnvironment(c.Instance)
+	if err != nil {
+		return err
+	}
+
+	jar, err := storkAuth(envURL)
+	if err != nil {
+		return err
+	}
+
+	instances, err := selectInstances(c.Instance, envURL, jar)
+	if err != nil {
+		return fmt.Errorf("%s: %w", envName, err)
+	}
+
+	err = os.MkdirAll(c.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, instance := range instances {
+		app, err := getApp(instance, envURL, jar)
+		if err != nil {
+			fmt.Printf("%s: %s: %s\n", envName, instance, err.Error())
+			failed++
+			continue
+		}
+
+		for _, daemon := range app.Details.Daemons {
+			if !wantDaemon(c.Daemon, daemon.Name) {
+				continue
+			}
+
+			config, err := getDaemonConfigByID(daemon.ID, envURL, jar)
+			if err != nil {
+				fmt.Printf("%s: %s: %s: %s\n", envName, instance, daemon.Name, err.Error())
+				failed++
+				continue
+			}
+
+			data, err := normalizeConfig(config.Config)
+			if err != nil {
+				return err
+			}
+			path := snapshotFile(c.Dir, instance, daemon.Name)
+			err = os.WriteFile(path, data, 0644)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Saved %s\n", path)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d configurations could not be saved", failed)
+	}
+	return nil
+}
+
+// errConfigDrift makes dhcli exit non-zero when live configurations no longer
+// match the baseline.
+var errConfigDrift = errors.New("configuration drift detected")
+
+// driftChanges compares a daemon's live configuration with its snapshot. The
+// live configuration goes through the snapshot format first so that both
+// sides had the same normalization.
+func driftChanges(baseline interface{}, live interface{}, ignore *regexp.Regexp) ([]ConfigChange, error) {
+	data, err := normalizeConfig(live)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&normalized)
+	if err != nil {
+		return nil, err
+	}
+	return filterChanges(diffConfig("", baseline, normalized), ignore), nil
+}
+
+// unsavedDaemons lists the daemons an instance runs that have no snapshot,
+// e.g. a dhcp6 daemon added since the baseline was taken.
+func unsavedDaemons(daemons []KeaDaemon, saved map[string]string) []string {
+	var names []string
+	for _, daemon := range daemons {
+		if _, ok := saved[daemon.Name]; !ok && wantDaemon(snapshotDaemons, daemon.Name) {
+			names = append(names, daemon.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *ConfigDriftCmd) Run() error {
+	ignore, err := diffIgnores(c.Ignore, true)
+	if err != nil {
+		return err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	// The snapshot files of each instance, by daemon.
+	snapshots := map[string]map[string]string{}
+	for _, path := range paths {
+		instance, daemon, ok := parseSnapshotFile(path)
+		if !ok || !matchesInstance(instance, c.Instance) {
+			continue
+		}
+		if snapshots[instance] == nil {
+			snapshots[instance] = map[string]string{}
+		}
+		snapshots[instance][daemon] = path
+	}
+	if len(snapshots) == 0 {
+		return fmt.Errorf("no snapshots found in %s", c.Dir)
+	}
+	instances := make([]string, 0, len(snapshots))
+	for instance := range snapshots {
+		instances = append(instances, instance)
+	}
+	sort.Strings(instances)
+
+	jars := map[string]*cookiejar.Jar{}
+	checked, drifted, failed := 0, 0, 0
+	for _, instance := range instances {
+		saved := snapshots[instance]
+
+		envName, envURL, err := instanceEnvironment(instance)
+		if err != nil {
+			return err
+		}
+		jar, ok := jars[envName]
+		if !ok {
+			jar, err = storkAuth(envURL)
+			if err != nil {
+				return fmt.Errorf("%s: %w", envName, err)
+			}
+			jars[envName] = jar
+		}
+
+		app, err := getApp(instance, envURL, jar)
+		if err != nil {
+			fmt.Printf("%s: %s: %s\n", envName, instance, err.Error())
+			checked += len(saved)
+			failed += len(saved)
+			continue
+		}
+		running := map[string]int{}
+		for _, daemon := range app.Details.Daemons {
+			running[daemon.Name] = daemon.ID
+		}
+
+		for _, daemon := range snapshotDaemons {
+			path, ok := saved[daemon]
+			if !ok {
+				continue
+			}
+			checked++
+
+			baseline, err := readSnapshot(path)
+			if err != nil {
+				fmt.Printf("%s: %s\n", path, err.Error())
+				failed++
+				continue
+			}
+
+			id, ok := running[daemon]
+			if !ok {
+				drifted++
+				fmt.Printf("\n%s %s: in %s but no longer running\n", instance, daemon, path)
+				continue
+			}
+			live, err := getDaemonConfigByID(id, envURL, jar)
+			if err != nil {
+				fmt.Printf("%s: %s: %s: %s\n", envName, instance, daemon, err.Error())
+				failed++
+				continue
+			}
+
+			changes, err := driftChanges(baseline, live.Config, ignore)
+			if err != nil {
+				return err
+			}
+			if len(changes) == 0 {
+				continue
+			}
+			drifted++
+			fmt.Printf("\n%s %s: %d changes since %s\n", instance, daemon, len(changes), path)
+			printChanges(changes, "baseline", "live")
+		}
+
+		for _, daemon := range unsavedDaemons(app.Details.Daemons, saved) {
+			checked++
+			drifted++
+			fmt.Printf("\n%s %s: running but not in the baseline\n", instance, daemon)
+		}
+	}
+
+	fmt.Printf("\n%d of %d configurations drifted", drifted, checked)
+	if failed > 0 {
+		fmt.Printf(", %d could not be checked", failed)
+	}
+	fmt.Println()
+
+	if drifted > 0 || failed > 0 {
+		return errConfigDrift
+	}
+	return nil
+}
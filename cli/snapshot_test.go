@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchInstances(t *testing.T) {
+	names := []string{"NYC4", "NYC3", "NYC31", "S2R8", "SFO1"}
+	tests := []struct {
+		selector string
+		want     []string
+		err      string
+	}{
+		{selector: "NYC", want: []string{"NYC3", "NYC31", "NYC4"}},
+		{selector: "nyc3", want: []string{"NYC3"}},
+		{selector: "NYC31", want: []string{"NYC31"}},
+		{selector: "s", want: []string{"S2R8", "SFO1"}},
+		{selector: "AMS", err: "no Kea instances match AMS"},
+	}
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			got, err := matchInstances(names, test.selector)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSnapshotFile(t *testing.T) {
+	tests := []struct {
+		path     string
+		instance string
+		daemon   string
+		ok       bool
+	}{
+		{path: "kea-baseline/NYC3.dhcp4.json", instance: "NYC3", daemon: "dhcp4", ok: true},
+		{path: "NYC3.ca.json", instance: "NYC3", daemon: "ca", ok: true},
+		{path: "kea.lab.d2.json", instance: "kea.lab", daemon: "d2", ok: true},
+		{path: "dhcp4.json"},
+		{path: "NYC3.netconf.json"},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			instance, daemon, ok := parseSnapshotFile(test.path)
+			if instance != test.instance || daemon != test.daemon || ok != test.ok {
+				t.Errorf("got %q, %q, %t", instance, daemon, ok)
+			}
+		})
+	}
+}
+
+func TestDriftChanges(t *testing.T) {
+	// A baseline as config snapshot stores it: scrubbed and key-sorted.
+	data, err := normalizeConfig(testConfig(t, `{"Dhcp4": {
+		"valid-lifetime": 4000,
+		"hooks-libraries": [{"library": "ha.so", "parameters": {"password": "old"}}],
+		"subnet4": [{"id": 1, "subnet": "10.0.0.0/24"}]
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := testConfig(t, string(data))
+
+	tests := []struct {
+		name   string
+		live   string
+		ignore []string
+		want   []string
+	}{
+		{
+			name: "unchanged but for the secret",
+			live: `{"Dhcp4": {"subnet4": [{"subnet": "10.0.0.0/24", "id": 1}], "valid-lifetime": 4000,
+				"hooks-libraries": [{"library": "ha.so", "parameters": {"password": "new"}}]}}`,
+		},
+		{
+			name: "changed and added",
+			live: `{"Dhcp4": {"valid-lifetime": 7200, "hooks-libraries": [{"library": "ha.so", "parameters": {"password": "old"}}],
+				"subnet4": [{"id": 1, "subnet": "10.0.0.0/24"}, {"id": 2, "subnet": "10.0.1.0/24"}]}}`,
+			want: []string{"+ Dhcp4.subnet4[id=2]", "~ Dhcp4.valid-lifetime"},
+		},
+		{
+			name: "ignored",
+			live: `{"Dhcp4": {"valid-lifetime": 7200, "hooks-libraries": [{"library": "ha.so", "parameters": {"password": "old"}}],
+				"subnet4": [{"id": 1, "subnet": "10.0.0.0/24"}]}}`,
+			ignore: []string{"Dhcp4.valid-lifetime"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ignore, err := compileIgnores(test.ignore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			changes, err := driftChanges(baseline, testConfig(t, test.live), ignore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, change := range changes {
+				got = append(got, change.Kind()+" "+change.Path)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnsavedDaemons(t *testing.T) {
+	daemons := []KeaDaemon{{Name: "dhcp4"}, {Name: "dhcp6"}, {Name: "d2"}, {Name: "ca"}, {Name: "netconf"}}
+	tests := []struct {
+		name  string
+		saved map[string]string
+		want  []string
+	}{
+		{name: "all saved", saved: map[string]string{"dhcp4": "a", "dhcp6": "b", "d2": "c", "ca": "d"}},
+		{name: "dhcp6 and d2 added", saved: map[string]string{"dhcp4": "a", "ca": "d"}, want: []string{"d2", "dhcp6"}},
+		{name: "nothing saved", saved: map[string]string{}, want: []string{"ca", "d2", "dhcp4", "dhcp6"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := unsavedDaemons(daemons, test.saved); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
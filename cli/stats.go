@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type StatsCmd struct {
+	Instance string        `kong:"arg='',name='kea-instance',completion='instances',help='e.g. NYC3, S2R8'"`
+	Daemon   string        `kong:"optional,short='d',default='dhcp4',enum='dhcp4,dhcp6',help='Daemon whose statistics to show: dhcp4 or dhcp6.'"`
+	Subnet   string        `kong:"optional,short='s',help='Show the statistics of this subnet (Kea subnet ID or prefix) instead of the global ones.'"`
+	Interval time.Duration `kong:"optional,short='i',help='Sample twice, this far apart, and show per-second rates (e.g. 10s).'"`
+}
+
+// KeaStatistics maps statistic names to samples of [value, timestamp],
+// newest first, as returned by statistic-get-all.
+type KeaStatistics map[string][][]interface{}
+
+// Value returns the latest value of a statistic.
+func (s KeaStatistics) Value(name string) (float64, bool) {
+	samples := s[name]
+	if len(samples) == 0 || len(samples[0]) == 0 {
+		return 0, false
+	}
+	number, ok := samples[0][0].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	value, err := number.Float64()
+	return value, err == nil
+}
+
+// Statistic categories, in display order. Errors come first as NAKs and
+// drops are what to look at when clients don't get addresses.
+var statCategories = []string{"Errors", "Received", "Sent", "Leases", "Other"}
+
+var subnetStat = regexp.MustCompile(`^subnet\[(\d+)\]\.(.+)$`)
+
+// statCategory files a statistic under one of statCategories.
+func statCategory(name string) string {
+	switch {
+	case strings.Contains(name, "nak"), strings.Contains(name, "drop"),
+		strings.Contains(name, "fail"), strings.Contains(name, "decline"):
+		return "Errors"
+	case strings.HasSuffix(name, "-received"):
+		return "Received"
+	case strings.HasSuffix(name, "-sent"):
+		return "Sent"
+	case strings.Contains(name, "addresses"), strings.Contains(name, "leases"),
+		strings.Contains(name, "-nas"), strings.Contains(name, "-pds"):
+		return "Leases"
+	}
+	return "Other"
+}
+
+// getStatistics fetches every statistic of a daemon from its control agent.
+func getStatistics(app *KeaAppDetail, daemon string) (KeaStatistics, error) {
+	stats := KeaStatistics{}
+	err := keaCommand(app, daemon, "statistic-get-all", nil, &stats)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// localSubnetID turns a Kea subnet ID or a prefix into the subnet ID the
+// instance knows the subnet by.
+func localSubnetID(subnet string, instance string, envURL *url.URL, jar *cookiejar.Jar) (string, error) {
+	if _, err := strconv.Atoi(subnet); err == nil {
+		return subnet, nil
+	}
+
+	subnets, err := getSubnets(envURL, jar)
+	if err != nil {
+		return "", err
+	}
+	for _, item := range subnets.Items {
+		if item.Subnet != subnet {
+			continue
+		}
+		for _, local := range item.LocalSubnets {
+			if local.AppName == instance {
+				return strconv.Itoa(local.SubnetID), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("subnet %s not found on %s", subnet, instance)
+}
+
+// selectStats returns the names of the global statistics, or of those of one
+// subnet with the subnet[id]. prefix removed.
+func selectStats(stats KeaStatistics, subnetID string) map[string]string {
+	names := map[string]string{}
+	for name := range stats {
+		m := subnetStat.FindStringSubmatch(name)
+		switch {
+		case subnetID == "" && m == nil:
+			names[name] = name
+		case subnetID != "" && m != nil && m[1] == subnetID:
+			names[m[2]] = name
+		}
+	}
+	return names
+}
+
+func formatStat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func (s *StatsCmd) Run() error {
+	envName, envURL, err := instanceEnvironment(s.Instance)
+	if err != nil {
+		return err
+	}
+
+	jar, err := storkAuth(envURL)
+	if err != nil {
+		return err
+	}
+
+	app, err := getApp(s.Instance, envURL, jar)
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", envName, s.Instance, err)
+	}
+
+	subnetID := ""
+	if s.Subnet != "" {
+		subnetID, err = localSubnetID(s.Subnet, s.Instance, envURL, jar)
+		if err != nil {
+			return err
+		}
+	}
+
+	first, err := getStatistics(app, s.Daemon)
+	if err != nil {
+		return err
+	}
+	stats, sampled := first, time.Now()
+	if s.Interval > 0 {
+		time.Sleep(s.Interval)
+		stats, err = getStatistics(app, s.Daemon)
+		if err != nil {
+			return err
+		}
+	}
+	elapsed := time.Since(sampled).Seconds()
+
+	names := selectStats(stats, subnetID)
+	if len(names) == 0 {
+		return fmt.Errorf("no statistics found for subnet %s on %s", s.Subnet, s.Instance)
+	}
+
+	grouped := map[string][]string{}
+	for label := range names {
+		category := statCategory(label)
+		grouped[category] = append(grouped[category], label)
+	}
+
+	title := fmt.Sprintf("%s %s", s.Instance, s.Daemon)
+	if subnetID != "" {
+		title += fmt.Sprintf(" subnet %s", subnetID)
+	}
+	fmt.Printf("\n%s: (%d statistics)\n", title, len(names))
+
+	header := []string{"Category", "Statistic", "Value"}
+	if s.Interval > 0 {
+		header = append(header, "Rate/s")
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetBorder(false)
+	table.SetAutoMergeCells(true)
+	table.SetHeader(header)
+	for _, category := range statCategories {
+		labels := grouped[category]
+		sort.Strings(labels)
+		for _, label := range labels {
+			value, ok := stats.Value(names[label])
+			if !ok {
+				continue
+			}
+			row := []string{category, label, formatStat(value)}
+			if s.Interval > 0 {
+				rate := ""
+				if before, ok := first.Value(names[label]); ok && elapsed > 0 {
+					rate = strconv.FormatFloat((value-before)/elapsed, 'f', 2, 64)
+				}
+				row = append(row, rate)
+			}
+			table.Append(row)
+		}
+	}
+	table.Render()
+	fmt.Println()
+	return nil
+}
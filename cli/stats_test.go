@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStatCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "pkt4-nak-sent", want: "Errors"},
+		{name: "pkt4-receive-drop", want: "Errors"},
+		{name: "pkt4-parse-failed", want: "Errors"},
+		{name: "declined-addresses", want: "Errors"},
+		{name: "pkt4-discover-received", want: "Received"},
+		{name: "pkt4-offer-sent", want: "Sent"},
+		{name: "assigned-addresses", want: "Leases"},
+		{name: "reclaimed-leases", want: "Leases"},
+		{name: "assigned-nas", want: "Leases"},
+		{name: "total-pds", want: "Leases"},
+		{name: "cumulative-assigned-addresses", want: "Leases"},
+		{name: "v4-allocation-fail", want: "Errors"},
+		{name: "pkt4-unknown-received", want: "Received"},
+		{name: "v4-reservation-conflicts", want: "Other"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := statCategory(test.name); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSelectStats(t *testing.T) {
+	var stats KeaStatistics
+	decoder := json.NewDecoder(strings.NewReader(`{
+		"pkt4-received": [[10, "2024-05-01 10:00:00.000000"]],
+		"subnet[1].assigned-addresses": [[5, "2024-05-01 10:00:00.000000"]],
+		"subnet[1].pool[0].assigned-addresses": [[3, "2024-05-01 10:00:00.000000"]],
+		"subnet[12].assigned-addresses": [[7, "2024-05-01 10:00:00.000000"]]
+	}`))
+	decoder.UseNumber()
+	if err := decoder.Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		subnet string
+		want   map[string]string
+	}{
+		{subnet: "", want: map[string]string{"pkt4-received": "pkt4-received"}},
+		{subnet: "1", want: map[string]string{
+			"assigned-addresses":         "subnet[1].assigned-addresses",
+			"pool[0].assigned-addresses": "subnet[1].pool[0].assigned-addresses",
+		}},
+		{subnet: "12", want: map[string]string{"assigned-addresses": "subnet[12].assigned-addresses"}},
+		{subnet: "2", want: map[string]string{}},
+	}
+	for _, test := range tests {
+		t.Run("subnet "+test.subnet, func(t *testing.T) {
+			if got := selectStats(stats, test.subnet); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if value, ok := stats.Value("subnet[12].assigned-addresses"); !ok || value != 7 {
+		t.Errorf("got value %v, %v, want 7", value, ok)
+	}
+	if _, ok := stats.Value("missing"); ok {
+		t.Error("value of a missing statistic")
+	}
+}
@@ -1,11 +1,9 @@
 package cli
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/olekukonko/tablewriter"
-	"io"
-	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"os"
 	"strconv"
@@ -16,15 +14,35 @@ import (
 type StatusCmd struct{}
 
 type KeaStatus struct {
+	Subnets4 struct {
+		Items []KeaSubnetItem `json:"items"`
+	} `json:"subnets4"`
+	Subnets6 struct {
+		Items []KeaSubnetItem `json:"items"`
+	} `json:"subnets6"`
 	DhcpDaemons []struct {
 		Active     bool   `json:"active"`
+		AppID      int    `json:"appId"`
 		AppName    string `json:"appName"`
 		AppVersion string `json:"appVersion"`
 		Machine    string `json:"machine"`
+		Name       string `json:"name"`
 		Uptime     int    `json:"uptime"`
 	} `json:"dhcpDaemons"`
 }
 
+// getOverview fetches the DHCP overview: daemon status and the most utilized
+// subnets.
+func getOverview(envURL *url.URL, jar *cookiejar.Jar) (*KeaStatus, error) {
+	k := KeaStatus{}
+	// http://netboot-stork-01.nyc3.internal.digitalocean.com/api/docs#operation/getDhcpOverview
+	err := storkGet("/api/overview", url.Values{}, envURL, jar, &k)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
 func (s *StatusCmd) Run() error {
 	// Because we are hitting both Prod/Staging we don't want to error out if one of them is unavailable
 	for envName, environment := range environments {
@@ -36,45 +54,16 @@ func (s *StatusCmd) Run() error {
 
 		jar, err := storkAuth(envURL)
 		if err != nil {
-			fmt.Printf("Error while creating cookie jar %s", err.Error())
+			fmt.Printf("Error authenticating with Stork: %s", err.Error())
 			return err
 		}
 
-		client := &http.Client{Jar: jar}
-
-		// http://netboot-stork-01.nyc3.internal.digitalocean.com/api/docs#operation/getDhcpOverview
-		envURL.Path = "/api/overview"
-
-		resp, err := client.Get(envURL.String())
+		k, err := getOverview(envURL, jar)
 		if err != nil {
-			fmt.Printf("Got error %s", err.Error())
-		}
-
-		if resp.StatusCode != 200 {
-			fmt.Printf("Error: Stork returned %d on search!", resp.StatusCode)
+			fmt.Printf("%s: error fetching overview: %s\n", envName, err.Error())
 			continue
 		}
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-
-		// Manually calling close due to for loop
-		// We aren't checking errors on this, because they don't really impact the outcome
-		_ = resp.Body.Close()
-
-		if len(body) == 0 {
-			fmt.Printf("Unexpected output from Stork server: %s", err.Error())
-		}
-
-		k := KeaStatus{}
-		jsonErr := json.Unmarshal(body, &k)
-		if jsonErr != nil {
-			fmt.Println(jsonErr)
-
-		}
-
 		table := tablewriter.NewWriter(os.Stdout)
 		table.SetAutoWrapText(false)
 		table.SetBorder(false)
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// KeaPools lists address pools as Kea writes them, e.g. 10.0.0.10-10.0.0.20
+// or 10.0.0.0/28. Stork reports pools as strings, or in newer versions as
+// objects with a pool field; both are accepted.
+type KeaPools []string
+
+func (p *KeaPools) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	pools := make(KeaPools, 0, len(raw))
+	for _, item := range raw {
+		var pool string
+		if err := json.Unmarshal(item, &pool); err == nil {
+			pools = append(pools, pool)
+			continue
+		}
+		var object struct {
+			Pool string `json:"pool"`
+		}
+		if err := json.Unmarshal(item, &object); err != nil {
+			return err
+		}
+		pools = append(pools, object.Pool)
+	}
+	*p = pools
+	return nil
+}
+
+// poolRange returns the first and last address of a pool.
+func poolRange(pool string) (netip.Addr, netip.Addr, error) {
+	pool = strings.ReplaceAll(pool, " ", "")
+	if first, last, ok := strings.Cut(pool, "-"); ok {
+		start, err := netip.ParseAddr(first)
+		if err != nil {
+			return netip.Addr{}, netip.Addr{}, err
+		}
+		end, err := netip.ParseAddr(last)
+		if err != nil {
+			return netip.Addr{}, netip.Addr{}, err
+		}
+		return start, end, nil
+	}
+
+	prefix, err := netip.ParsePrefix(pool)
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, err
+	}
+	return prefixRange(prefix.Masked())
+}
+
+// prefixRange returns the first and last address of a prefix.
+func prefixRange(prefix netip.Prefix) (netip.Addr, netip.Addr, error) {
+	first := prefix.Masked().Addr()
+	last := first.AsSlice()
+	for bit := prefix.Bits(); bit < len(last)*8; bit++ {
+		last[bit/8] |= 0x80 >> (bit % 8)
+	}
+	end, ok := netip.AddrFromSlice(last)
+	if !ok {
+		return netip.Addr{}, netip.Addr{}, fmt.Errorf("invalid prefix %s", prefix)
+	}
+	return first, end, nil
+}
+
+// AllPools returns the pools of the subnet on every instance serving it.
+func (s *KeaSubnetItem) AllPools() []string {
+	seen := map[string]bool{}
+	var pools []string
+	add := func(list KeaPools) {
+		for _, pool := range list {
+			if !seen[pool] {
+				seen[pool] = true
+				pools = append(pools, pool)
+			}
+		}
+	}
+	add(s.Pools)
+	for _, local := range s.LocalSubnets {
+		add(local.Pools)
+	}
+	return pools
+}
+
+// PoolContaining returns the pool an address falls in, or "".
+func (s *KeaSubnetItem) PoolContaining(addr netip.Addr) string {
+	for _, pool := range s.AllPools() {
+		start, end, err := poolRange(pool)
+		if err != nil {
+			continue
+		}
+		if addr.Compare(start) >= 0 && addr.Compare(end) <= 0 {
+			return pool
+		}
+	}
+	return ""
+}
+
+// parseTarget reads an IP address or CIDR typed by the user as a prefix; a
+// single address is a full-length prefix.
+func parseTarget(text string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(text); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(text)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%s is not an IP address or CIDR", text)
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// subnetID looks up the Stork ID of the subnet that is exactly prefix.
+func subnetID(prefix netip.Prefix, env stork) (int, error) {
+	match, err := findSubnet(prefix, []stork{env})
+	if err != nil {
+		return 0, err
+	}
+	if match.Prefix != prefix {
+		return 0, fmt.Errorf("no subnet %s", prefix)
+	}
+	return match.Subnet.SubnetID, nil
+}
+
+// SubnetMatch is the subnet containing an address or CIDR.
+type SubnetMatch struct {
+	Env    stork
+	Subnet KeaSubnetItem
+	Prefix netip.Prefix
+	// Pool is the pool containing a single address, or "".
+	Pool string
+}
+
+// findSubnet finds the most specific Kea subnet containing target in any of
+// the environments.
+func findSubnet(target netip.Prefix, envs []stork) (*SubnetMatch, error) {
+	var best *SubnetMatch
+	for _, env := range envs {
+		subnets, err := getSubnets(env.url, env.jar)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", env.name, err)
+		}
+
+		for _, item := range subnets.Items {
+			prefix, err := netip.ParsePrefix(item.Subnet)
+			if err != nil {
+				continue
+			}
+			prefix = prefix.Masked()
+			if prefix.Bits() > target.Bits() || !prefix.Contains(target.Addr()) {
+				continue
+			}
+			if best == nil || prefix.Bits() > best.Prefix.Bits() {
+				best = &SubnetMatch{Env: env, Subnet: item, Prefix: prefix}
+			}
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no subnet contains %s", target)
+	}
+	if target.IsSingleIP() {
+		best.Pool = best.Subnet.PoolContaining(target.Addr())
+	}
+	return best, nil
+}
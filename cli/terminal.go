@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ANSI escape sequences used by the full-screen UI and colored output.
+const (
+	ansiAltScreen  = "\x1b[?1049h"
+	ansiMainScreen = "\x1b[?1049l"
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+	ansiHome       = "\x1b[H"
+	ansiClearLine  = "\x1b[K"
+	ansiReverse    = "\x1b[7m"
+	ansiBold       = "\x1b[1m"
+	ansiDim        = "\x1b[2m"
+	ansiRed        = "\x1b[31m"
+	ansiYellow     = "\x1b[33m"
+	ansiReset      = "\x1b[0m"
+)
+
+// rawTerminal puts the terminal on stdin into raw mode and returns a function
+// restoring the previous settings.
+func rawTerminal() (func(), error) {
+	if !isTerminal(os.Stdin) {
+		return nil, errors.New("stdin is not a terminal")
+	}
+
+	cmd := exec.Command("stty", "-g")
+	cmd.Stdin = os.Stdin
+	saved, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not read terminal settings: %w", err)
+	}
+
+	if err = stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("could not switch terminal to raw mode: %w", err)
+	}
+	return func() { _ = stty(strings.TrimSpace(string(saved))) }, nil
+}
+
+// terminalSize returns the number of rows and columns of the terminal on
+// stdin, falling back to 24x80.
+func terminalSize() (rows int, cols int) {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err == nil {
+		if _, err = fmt.Sscan(string(out), &rows, &cols); err == nil && rows > 0 && cols > 0 {
+			return rows, cols
+		}
+	}
+	return 24, 80
+}
+
+// fit truncates or pads s to exactly width columns, replacing control
+// characters that would upset the layout.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, s))
+	if len(runes) > width {
+		if width == 1 {
+			return "…"
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no. Without a
+// terminal there is nobody to ask, so it fails and the caller should offer a
+// flag such as --yes instead.
+func confirm(question string) (bool, error) {
+	if !isTerminal(os.Stdin) {
+		return false, errors.New("stdin is not a terminal; pass --yes to confirm")
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
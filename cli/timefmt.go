@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+)
+
+// displayLocation is the time zone times are shown in.
+var displayLocation = time.Local
+
+// SetUTC shows times in UTC instead of local time.
+func SetUTC(utc bool) {
+	if utc {
+		displayLocation = time.UTC
+	} else {
+		displayLocation = time.Local
+	}
+}
+
+// infiniteLifetime is the valid lifetime Kea gives leases that never expire.
+const infiniteLifetime = 0xffffffff
+
+// leaseExpiry returns when a lease with the given cltt and valid lifetime
+// expires: the zero time if it has no transaction time or never expires.
+func leaseExpiry(cltt int64, validLifetime int64) time.Time {
+	if cltt == 0 || validLifetime == infiniteLifetime {
+		return time.Time{}
+	}
+	return time.Unix(cltt+validLifetime, 0)
+}
+
+// formatTime renders a timestamp in the display time zone, or "-" for the
+// zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	format := "2006-01-02 15:04:05"
+	if displayLocation == time.UTC {
+		format += " UTC"
+	}
+	return t.In(displayLocation).Format(format)
+}
+
+// humanDuration renders a duration with its two most significant units, e.g.
+// 2d 3h or 5m 10s.
+func humanDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	d = d.Round(time.Second)
+
+	days := d / (24 * time.Hour)
+	hours := (d % (24 * time.Hour)) / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	seconds := (d % time.Minute) / time.Second
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, seconds)
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+// formatTimeRelative renders a timestamp followed by how long ago or ahead it
+// is, e.g. "2024-05-01 10:00:00 (in 3h 12m)", or "-" for the zero time.
+func formatTimeRelative(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return fmt.Sprintf("%s (%s)", formatTime(t), relativeTime(t))
+}
+
+// formatExpiry renders when a lease expires with the time remaining, "never"
+// for an infinite lifetime, or "-" if the lease has no transaction time.
+func formatExpiry(cltt int64, validLifetime int64) string {
+	if cltt != 0 && validLifetime == infiniteLifetime {
+		return "never"
+	}
+	return formatTimeRelative(leaseExpiry(cltt, validLifetime))
+}
+
+// relativeTime describes t relative to now, e.g. "in 3h 12m" or "5m 10s ago".
+func relativeTime(t time.Time) string {
+	d := time.Until(t)
+	if d >= 0 {
+		return "in " + humanDuration(d)
+	}
+	return humanDuration(d) + " ago"
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatTime(t *testing.T) {
+	saved := displayLocation
+	t.Cleanup(func() { displayLocation = saved })
+
+	at := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		location *time.Location
+		time     time.Time
+		want     string
+	}{
+		{name: "local", location: time.FixedZone("EDT", -4*3600), time: at, want: "2024-05-01 06:30:00"},
+		{name: "utc", location: time.UTC, time: at, want: "2024-05-01 10:30:00 UTC"},
+		{name: "zero local", location: time.FixedZone("EDT", -4*3600), want: "-"},
+		{name: "zero utc", location: time.UTC, want: "-"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			displayLocation = test.location
+			if got := formatTime(test.time); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSetUTC(t *testing.T) {
+	saved := displayLocation
+	t.Cleanup(func() { displayLocation = saved })
+
+	SetUTC(true)
+	if displayLocation != time.UTC {
+		t.Errorf("--utc shows times in %v", displayLocation)
+	}
+	SetUTC(false)
+	if displayLocation != time.Local {
+		t.Errorf("without --utc times are shown in %v", displayLocation)
+	}
+}
+
+func TestFormatExpiry(t *testing.T) {
+	saved := displayLocation
+	t.Cleanup(func() { displayLocation = saved })
+	displayLocation = time.UTC
+
+	now := time.Now().Unix()
+	tests := []struct {
+		name     string
+		cltt     int64
+		lifetime int64
+		want     string
+	}{
+		{name: "no cltt", cltt: 0, lifetime: 3600, want: "-"},
+		{name: "infinite", cltt: now, lifetime: infiniteLifetime, want: "never"},
+		{name: "no cltt or lifetime", cltt: 0, lifetime: infiniteLifetime, want: "-"},
+		{name: "ahead", cltt: now, lifetime: 7230, want: "UTC (in 2h 0m)"},
+		{name: "past", cltt: now - 7230, lifetime: 3600, want: "UTC (1h 0m ago)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := formatExpiry(test.cltt, test.lifetime); !strings.HasSuffix(got, test.want) || strings.HasPrefix(got, "1970") {
+				t.Errorf("got %q, want ...%q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLeaseItemTimes(t *testing.T) {
+	lease := LeaseItem{State: leaseDefault, ValidLifetime: 3600}
+	if !lease.LastTransaction().IsZero() || !lease.Expiry().IsZero() {
+		t.Errorf("lease without cltt has times %v, %v", lease.LastTransaction(), lease.Expiry())
+	}
+	if lease.ExpiredUnreclaimed() {
+		t.Error("lease without cltt is expired")
+	}
+
+	lease.Cltt = time.Now().Add(-2 * time.Hour).Unix()
+	if !lease.ExpiredUnreclaimed() {
+		t.Error("lease expired an hour ago is not expired")
+	}
+	lease.ValidLifetime = infiniteLifetime
+	if lease.ExpiredUnreclaimed() {
+		t.Error("lease with an infinite lifetime is expired")
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{in: 0, want: "0s"},
+		{in: 42 * time.Second, want: "42s"},
+		{in: 5*time.Minute + 10*time.Second, want: "5m 10s"},
+		{in: 3*time.Hour + 12*time.Minute + 59*time.Second, want: "3h 12m"},
+		{in: -(50*time.Hour + 30*time.Minute), want: "2d 2h"},
+	}
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			if got := humanDuration(test.in); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
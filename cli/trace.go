@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type TraceLevel int
+
+const (
+	// TraceOff disables HTTP tracing.
+	TraceOff TraceLevel = iota
+	// TraceRequests logs method, URL, status, latency and body size.
+	TraceRequests
+	// TraceBodies additionally logs headers and bodies.
+	TraceBodies
+)
+
+var (
+	traceLevel  = TraceOff
+	traceLogger = log.New(os.Stderr, "", log.Ltime|log.Lmicroseconds)
+
+	// Values of these JSON keys never make it into the trace output.
+	secretFields = regexp.MustCompile(`("[A-Za-z_-]*(?i:password|secret|token)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// Headers whose values are replaced in the trace output.
+	secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Vault-Token"}
+)
+
+// SetTraceLevel controls how much of the Stork HTTP traffic is logged to
+// stderr.
+func SetTraceLevel(level TraceLevel) {
+	traceLevel = level
+}
+
+// storkClient returns an HTTP client for talking to Stork, traced according
+// to the current trace level.
+func storkClient(jar *cookiejar.Jar) *http.Client {
+	client := &http.Client{Transport: tracingTransport{next: http.DefaultTransport}}
+	if jar != nil {
+		client.Jar = jar
+	}
+	return client
+}
+
+type tracingTransport struct {
+	next http.RoundTripper
+}
+
+func (t tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if traceLevel == TraceOff {
+		return t.next.RoundTrip(req)
+	}
+
+	traceLogger.Printf("> %s %s", req.Method, req.URL.Redacted())
+	if traceLevel >= TraceBodies {
+		traceHeaders(">", req.Header)
+		if req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				data, _ := io.ReadAll(body)
+				traceBody(">", data)
+			}
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	latency := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		traceLogger.Printf("< %s %s failed after %s: %v", req.Method, req.URL.Redacted(), latency, err)
+		return nil, err
+	}
+
+	// Buffer the body so its size can be reported; callers read it all
+	// anyway.
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	traceLogger.Printf("< %s %s: %d in %s (%d bytes)", req.Method, req.URL.Redacted(), resp.StatusCode, latency, len(data))
+	if traceLevel >= TraceBodies {
+		traceHeaders("<", resp.Header)
+		traceBody("<", data)
+	}
+	return resp, nil
+}
+
+func traceHeaders(direction string, header http.Header) {
+	for name, values := range header {
+		for _, secret := range secretHeaders {
+			if strings.EqualFold(name, secret) {
+				values = []string{"[REDACTED]"}
+			}
+		}
+		traceLogger.Printf("%s %s: %s", direction, name, strings.Join(values, ", "))
+	}
+}
+
+func traceBody(direction string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	traceLogger.Printf("%s %s", direction, redactBody(body))
+}
+
+// redactBody blanks out password and token values in a JSON body.
+func redactBody(body []byte) []byte {
+	return secretFields.ReplaceAll(body, []byte(`$1"[REDACTED]"`))
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "login", body: `{"useremail":"me","userpassword":"hunter2"}`, want: `{"useremail":"me","userpassword":"[REDACTED]"}`},
+		{name: "spacing", body: `{"password" : "a b"}`, want: `{"password" : "[REDACTED]"}`},
+		{name: "escaped quote", body: `{"secret":"a\"b","x":1}`, want: `{"secret":"[REDACTED]","x":1}`},
+		{name: "case and prefix", body: `{"api-Token":"abc"}`, want: `{"api-Token":"[REDACTED]"}`},
+		{name: "nested", body: `{"a":{"client_secret":"abc"},"b":"token"}`, want: `{"a":{"client_secret":"[REDACTED]"},"b":"token"}`},
+		{name: "non-string", body: `{"password-length":12}`, want: `{"password-length":12}`},
+		{name: "nothing secret", body: `{"hostname":"password"}`, want: `{"hostname":"password"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := string(redactBody([]byte(test.body))); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTraceHeaders(t *testing.T) {
+	var out bytes.Buffer
+	saved := traceLogger
+	traceLogger = log.New(&out, "", 0)
+	t.Cleanup(func() { traceLogger = saved })
+
+	traceHeaders(">", http.Header{
+		"Authorization": {"Basic c2VjcmV0"},
+		"Cookie":        {"session=abc"},
+		"X-Vault-Token": {"s.abc"},
+		"Accept":        {"application/json"},
+	})
+
+	got := out.String()
+	for _, secret := range []string{"c2VjcmV0", "session=abc", "s.abc"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("%q leaked into the trace:\n%s", secret, got)
+		}
+	}
+	if !strings.Contains(got, "> Accept: application/json") {
+		t.Errorf("harmless header missing from the trace:\n%s", got)
+	}
+}
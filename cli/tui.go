@@ -0,0 +1,653 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/cookiejar"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TuiCmd struct {
+	Interval time.Duration `kong:"optional,short='i',default='10s',help='How often to refresh status, events and logs.'"`
+}
+
+type KeaEvents struct {
+	Total int `json:"total"`
+	Items []struct {
+		CreatedAt time.Time `json:"createdAt"`
+		Text      string    `json:"text"`
+		Level     int       `json:"level"`
+	} `json:"items"`
+}
+
+var (
+	// Stork event text embeds objects as tags like <app id="1" name="kea@host">.
+	eventTag     = regexp.MustCompile(`</?\w+[^>]*>`)
+	eventTagName = regexp.MustCompile(`name="([^"]*)"`)
+)
+
+// eventText replaces the tags in a Stork event with the names they carry.
+func eventText(text string) string {
+	return eventTag.ReplaceAllStringFunc(text, func(tag string) string {
+		if m := eventTagName.FindStringSubmatch(tag); m != nil {
+			return m[1]
+		}
+		return ""
+	})
+}
+
+func getEvents(envURL *url.URL, jar *cookiejar.Jar) (*KeaEvents, error) {
+	query := url.Values{}
+	query.Set("limit", "50")
+
+	e := KeaEvents{}
+	err := storkGet("/api/events", query, envURL, jar, &e)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching events: %w", err)
+	}
+	return &e, nil
+}
+
+// Keys understood by the dashboard.
+const (
+	keyUp      = "up"
+	keyDown    = "down"
+	keyPgUp    = "pgup"
+	keyPgDn    = "pgdn"
+	keyEnter   = "enter"
+	keyBack    = "back"
+	keyRefresh = "refresh"
+	keyQuit    = "quit"
+)
+
+// dashboardEnv is what the dashboard knows about one Stork environment.
+type dashboardEnv struct {
+	name     string
+	url      *url.URL
+	jar      *cookiejar.Jar
+	overview *KeaStatus
+	events   *KeaEvents
+	err      error
+}
+
+// dashboardRow is a selectable line of a dashboard list. Rows with an open
+// function can be drilled into.
+type dashboardRow struct {
+	text  string
+	color string
+	open  func() (*dashboardList, error)
+
+	// Set on the daemon rows of the root list.
+	env      *dashboardEnv
+	instance string
+	key      string
+}
+
+type dashboardList struct {
+	title    string
+	rows     []dashboardRow
+	selected int
+}
+
+type dashboard struct {
+	mu       sync.Mutex
+	envs     []*dashboardEnv
+	stack    []*dashboardList
+	logKey   string
+	logs     []string
+	logErr   error
+	status   string
+	redrawCh chan struct{}
+}
+
+func (t *TuiCmd) Run() error {
+	d := &dashboard{
+		stack:    []*dashboardList{{title: "Kea daemons"}},
+		redrawCh: make(chan struct{}, 1),
+	}
+
+	// Authenticate before taking over the terminal, as this may prompt.
+	var names []string
+	for name := range environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env := &dashboardEnv{name: name}
+		env.url, env.err = url.Parse(environments[name])
+		if env.err == nil {
+			env.jar, env.err = storkAuth(env.url)
+		}
+		d.envs = append(d.envs, env)
+	}
+
+	restore, err := rawTerminal()
+	if err != nil {
+		return fmt.Errorf("tui needs an interactive terminal: %w", err)
+	}
+	defer restore()
+	fmt.Print(ansiAltScreen + ansiHideCursor)
+	defer fmt.Print(ansiShowCursor + ansiMainScreen)
+
+	keys := readKeys()
+	go func() {
+		for {
+			d.refresh()
+			time.Sleep(t.Interval)
+		}
+	}()
+
+	// Redraw periodically too, which picks up terminal resizes.
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		d.draw()
+		select {
+		case key, ok := <-keys:
+			if !ok || key == keyQuit {
+				return nil
+			}
+			d.handleKey(key)
+		case <-d.redrawCh:
+		case <-ticker.C:
+		}
+	}
+}
+
+// readKeys decodes keypresses from the raw terminal.
+func readKeys() <-chan string {
+	keys := make(chan string)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				return
+			}
+			var key string
+			switch in := string(buf[:n]); in {
+			case "\x1b[A", "\x1bOA", "k":
+				key = keyUp
+			case "\x1b[B", "\x1bOB", "j":
+				key = keyDown
+			case "\x1b[5~":
+				key = keyPgUp
+			case "\x1b[6~", " ":
+				key = keyPgDn
+			case "\r", "\n", "\x1b[C", "\x1bOC", "l":
+				key = keyEnter
+			case "\x1b", "\x7f", "\b", "\x1b[D", "\x1bOD", "h":
+				key = keyBack
+			case "r":
+				key = keyRefresh
+			case "q", "\x03", "\x04":
+				key = keyQuit
+			default:
+				continue
+			}
+			keys <- key
+		}
+	}()
+	return keys
+}
+
+func (d *dashboard) redraw() {
+	select {
+	case d.redrawCh <- struct{}{}:
+	default:
+	}
+}
+
+// refresh reloads the overview and events of every environment, then the
+// log tail of the selected instance.
+func (d *dashboard) refresh() {
+	for _, env := range d.envs {
+		if env.jar == nil {
+			continue
+		}
+		overview, err := getOverview(env.url, env.jar)
+		events, eventsErr := getEvents(env.url, env.jar)
+
+		d.mu.Lock()
+		if err == nil {
+			env.overview = overview
+			env.err = eventsErr
+		} else {
+			env.err = err
+		}
+		if eventsErr == nil {
+			env.events = events
+		}
+		d.mu.Unlock()
+	}
+
+	d.mu.Lock()
+	d.rebuildDaemons()
+	d.mu.Unlock()
+	d.redraw()
+
+	d.refreshLogs()
+}
+
+// refreshLogs fetches the log tail of the instance selected in the root list.
+func (d *dashboard) refreshLogs() {
+	d.mu.Lock()
+	row := d.selectedDaemon()
+	d.mu.Unlock()
+	if row == nil {
+		return
+	}
+
+	entries, err := getLogEntries(row.instance, row.env.url, row.env.jar)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// The selection may have moved on while we were fetching.
+	if current := d.selectedDaemon(); current == nil || current.env != row.env || current.instance != row.instance {
+		return
+	}
+	d.logKey = row.env.name + "/" + row.instance
+	d.logErr = err
+	if err == nil {
+		d.logs = entries.Contents
+	}
+	d.redraw()
+}
+
+// rebuildDaemons refills the root list from the latest overviews, keeping the
+// selection on the same instance. Callers hold d.mu.
+func (d *dashboard) rebuildDaemons() {
+	root := d.stack[0]
+	var selectedKey string
+	if row := d.selectedDaemon(); row != nil {
+		selectedKey = row.key
+	}
+
+	root.rows = nil
+	root.selected = 0
+	for _, env := range d.envs {
+		if env.overview == nil {
+			continue
+		}
+		for _, daemon := range env.overview.DhcpDaemons {
+			daemon := daemon
+			env := env
+			state, color := "up  ", ""
+			if !daemon.Active {
+				state, color = "DOWN", ansiRed
+			}
+			host := strings.Replace(daemon.Machine, ".internal.digitalocean.com", "", 1)
+			row := dashboardRow{
+				text:     fmt.Sprintf("%-10s %s %-8s %-6s %-8s %s", env.name, state, daemon.AppName, daemon.Name, daemon.AppVersion, host),
+				color:    color,
+				env:      env,
+				instance: daemon.AppName,
+				key:      env.name + "/" + daemon.AppName + "/" + daemon.Name,
+				open: func() (*dashboardList, error) {
+					return openSubnets(env, daemon.AppID, daemon.AppName)
+				},
+			}
+			if row.key == selectedKey {
+				root.selected = len(root.rows)
+			}
+			root.rows = append(root.rows, row)
+		}
+	}
+}
+
+// selectedDaemon returns the selected row of the root list. Callers hold d.mu.
+func (d *dashboard) selectedDaemon() *dashboardRow {
+	root := d.stack[0]
+	if root.selected < len(root.rows) {
+		return &root.rows[root.selected]
+	}
+	return nil
+}
+
+// selectedEnv is the environment of the selected daemon, or the first one.
+// Callers hold d.mu.
+func (d *dashboard) selectedEnv() *dashboardEnv {
+	if row := d.selectedDaemon(); row != nil {
+		return row.env
+	}
+	if len(d.envs) > 0 {
+		return d.envs[0]
+	}
+	return nil
+}
+
+func (d *dashboard) handleKey(key string) {
+	d.mu.Lock()
+	list := d.stack[len(d.stack)-1]
+	before := list.selected
+	d.status = ""
+
+	switch key {
+	case keyUp:
+		list.selected--
+	case keyDown:
+		list.selected++
+	case keyPgUp:
+		list.selected -= 10
+	case keyPgDn:
+		list.selected += 10
+	case keyBack:
+		if len(d.stack) > 1 {
+			d.stack = d.stack[:len(d.stack)-1]
+		}
+	case keyRefresh:
+		d.status = "Refreshing..."
+		go d.refresh()
+	case keyEnter:
+		if list.selected < len(list.rows) && list.rows[list.selected].open != nil {
+			d.status = "Loading..."
+			go d.open(list, list.rows[list.selected].open)
+		}
+	}
+
+	if list.selected >= len(list.rows) {
+		list.selected = len(list.rows) - 1
+	}
+	if list.selected < 0 {
+		list.selected = 0
+	}
+	moved := len(d.stack) == 1 && list.selected != before
+	d.mu.Unlock()
+
+	if moved {
+		go d.refreshLogs()
+	}
+}
+
+// open drills into a row of list in the background, so keys and redraws keep
+// being handled while the lookup runs. The result is dropped if the user has
+// left list in the meantime.
+func (d *dashboard) open(list *dashboardList, open func() (*dashboardList, error)) {
+	next, err := open()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stack[len(d.stack)-1] != list {
+		return
+	}
+	if err != nil {
+		d.status = err.Error()
+	} else {
+		d.status = ""
+		d.stack = append(d.stack, next)
+	}
+	d.redraw()
+}
+
+// openSubnets lists the subnets served by a Kea app.
+func openSubnets(env *dashboardEnv, appID int, appName string) (*dashboardList, error) {
+	s, err := getSubnets(env.url, env.jar)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &dashboardList{title: appName + " subnets"}
+	for _, subnet := range s.Items {
+		for _, local := range subnet.LocalSubnets {
+			if local.AppID != appID {
+				continue
+			}
+			subnet := subnet
+			list.rows = append(list.rows, dashboardRow{
+				text:  fmt.Sprintf("%-20s %5.1f%%  id %d", subnet.Subnet, subnet.AddrUtilization, local.SubnetID),
+				color: utilizationColor(subnet.AddrUtilization),
+				open: func() (*dashboardList, error) {
+					return openReservations(env, subnet.Subnet)
+				},
+			})
+			break
+		}
+	}
+	return list, nil
+}
+
+// openReservations lists the host reservations in a subnet.
+func openReservations(env *dashboardEnv, prefix string) (*dashboardList, error) {
+	subnet, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	items, err := retryStale(env.url, func() ([]KeaHost, error) {
+		id, err := subnetID(subnet.Masked(), stork{name: env.name, url: env.url, jar: env.jar})
+		if err != nil {
+			return nil, err
+		}
+		query := url.Values{}
+		query.Set("subnetId", strconv.Itoa(id))
+		k, err := getReservations(query, env.url, env.jar)
+		if err != nil {
+			return nil, err
+		}
+		return k.Items, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := &dashboardList{title: prefix + " reservations"}
+	for _, item := range items {
+		if len(item.AddressReservations) == 0 {
+			continue
+		}
+		address := item.AddressReservations[0].Address
+		var mac string
+		if len(item.HostIdentifiers) > 0 {
+			mac = item.HostIdentifiers[0].IdHexValue
+		}
+		list.rows = append(list.rows, dashboardRow{
+			text: fmt.Sprintf("%-18s %s", address, mac),
+			open: func() (*dashboardList, error) {
+				return openLeases(env, strings.Split(address, "/")[0])
+			},
+		})
+	}
+	return list, nil
+}
+
+// openLeases lists the leases matching an address.
+func openLeases(env *dashboardEnv, address string) (*dashboardList, error) {
+	l, err := getLeases(address, env.url, env.jar)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &dashboardList{title: address + " leases"}
+	for _, lease := range l.Items {
+		state, color := "Active", ""
+		if lease.State != 0 {
+			state, color = "Inactive", ansiYellow
+		}
+		list.rows = append(list.rows, dashboardRow{
+			text:  fmt.Sprintf("%-8s %-16s %-17s %-8s %s", lease.AppName, lease.IpAddress, lease.HwAddress, state, lease.Hostname),
+			color: color,
+		})
+	}
+	if len(list.rows) == 0 {
+		list.rows = append(list.rows, dashboardRow{text: "No leases found"})
+	}
+	return list, nil
+}
+
+func utilizationColor(utilization float64) string {
+	switch {
+	case utilization >= 90:
+		return ansiRed
+	case utilization >= 75:
+		return ansiYellow
+	}
+	return ""
+}
+
+// pane is a titled block of lines rendered into a fixed area.
+type pane struct {
+	title    string
+	rows     []dashboardRow
+	selected int // -1 for no selection
+}
+
+// render returns exactly height lines of exactly width columns.
+func (p pane) render(width int, height int) []string {
+	if height <= 0 {
+		return nil
+	}
+	lines := []string{ansiBold + fit(" "+p.title, width) + ansiReset}
+
+	// Scroll so the selection stays visible.
+	visible := height - 1
+	offset := 0
+	if p.selected >= visible {
+		offset = p.selected - visible + 1
+	}
+
+	for i := offset; i < offset+visible; i++ {
+		if i >= len(p.rows) {
+			lines = append(lines, fit("", width))
+			continue
+		}
+		text := fit(" "+p.rows[i].text, width)
+		switch {
+		case i == p.selected:
+			text = ansiReverse + p.rows[i].color + text + ansiReset
+		case p.rows[i].color != "":
+			text = p.rows[i].color + text + ansiReset
+		}
+		lines = append(lines, text)
+	}
+	return lines
+}
+
+func (d *dashboard) draw() {
+	rows, cols := terminalSize()
+
+	d.mu.Lock()
+	list := d.stack[len(d.stack)-1]
+	var breadcrumb []string
+	for _, l := range d.stack {
+		breadcrumb = append(breadcrumb, l.title)
+	}
+	left := pane{title: strings.Join(breadcrumb, " › "), rows: list.rows, selected: list.selected}
+	if len(list.rows) == 0 {
+		left.rows = []dashboardRow{{text: "Nothing to show"}}
+		if len(d.stack) == 1 {
+			left.rows[0].text = "Loading..."
+		}
+		left.selected = -1
+	}
+
+	utilization := pane{title: "Subnet utilization", selected: -1}
+	events := pane{title: "Recent events", selected: -1}
+	if env := d.selectedEnv(); env != nil {
+		utilization.title += " — " + env.name
+		events.title += " — " + env.name
+		if env.err != nil {
+			events.rows = append(events.rows, dashboardRow{text: env.err.Error(), color: ansiRed})
+		}
+		if env.overview != nil {
+			subnets := append([]KeaSubnetItem{}, env.overview.Subnets4.Items...)
+			subnets = append(subnets, env.overview.Subnets6.Items...)
+			sort.SliceStable(subnets, func(i, j int) bool {
+				return subnets[i].AddrUtilization > subnets[j].AddrUtilization
+			})
+			for _, subnet := range subnets {
+				var apps []string
+				for _, local := range subnet.LocalSubnets {
+					apps = append(apps, local.AppName)
+				}
+				utilization.rows = append(utilization.rows, dashboardRow{
+					text:  fmt.Sprintf("%5.1f%%  %-20s %s", subnet.AddrUtilization, subnet.Subnet, strings.Join(apps, ",")),
+					color: utilizationColor(subnet.AddrUtilization),
+				})
+			}
+		}
+		if env.events != nil {
+			for _, event := range env.events.Items {
+				color := ""
+				switch event.Level {
+				case 1:
+					color = ansiYellow
+				case 2:
+					color = ansiRed
+				}
+				events.rows = append(events.rows, dashboardRow{
+					text:  event.CreatedAt.Local().Format("01-02 15:04:05") + "  " + eventText(event.Text),
+					color: color,
+				})
+			}
+		}
+	}
+
+	logs := pane{title: "Log tail", selected: -1}
+	if row := d.selectedDaemon(); row != nil {
+		logs.title += " — " + row.instance + " (" + row.env.name + ")"
+		if d.logKey == row.env.name+"/"+row.instance {
+			if d.logErr != nil {
+				logs.rows = append(logs.rows, dashboardRow{text: d.logErr.Error(), color: ansiRed})
+			}
+			for _, line := range d.logs {
+				logs.rows = append(logs.rows, dashboardRow{text: line, color: logColor(line)})
+			}
+		}
+	}
+
+	status := d.status
+	d.mu.Unlock()
+
+	// Layout: title, list on the left, utilization and events on the right,
+	// log tail along the bottom, status line last.
+	logHeight := (rows - 2) / 3
+	if logHeight < 4 {
+		logHeight = 4
+	}
+	mainHeight := rows - 2 - logHeight
+	leftWidth := cols / 2
+	rightWidth := cols - leftWidth - 1
+	utilHeight := mainHeight / 2
+
+	// Show the newest log lines.
+	if extra := len(logs.rows) - (logHeight - 1); extra > 0 {
+		logs.rows = logs.rows[extra:]
+	}
+
+	var out bytes.Buffer
+	out.WriteString(ansiHome)
+	out.WriteString(ansiReverse + fit(" dhcli dashboard", cols) + ansiReset + "\r\n")
+
+	leftLines := left.render(leftWidth, mainHeight)
+	rightLines := append(utilization.render(rightWidth, utilHeight), events.render(rightWidth, mainHeight-utilHeight)...)
+	for i := 0; i < mainHeight; i++ {
+		out.WriteString(leftLines[i] + "│" + rightLines[i] + "\r\n")
+	}
+	for _, line := range logs.render(cols, logHeight) {
+		out.WriteString(line + "\r\n")
+	}
+
+	if status == "" {
+		status = "↑/↓ select  ⏎ drill down  esc back  r refresh  q quit"
+	}
+	out.WriteString(ansiReverse + fit(" "+status, cols) + ansiReset)
+	_, _ = os.Stdout.Write(out.Bytes())
+}
+
+// logColor highlights Kea log lines by severity.
+func logColor(line string) string {
+	entry, ok := parseLogLine(line, time.Local)
+	if !ok {
+		return ""
+	}
+	return severityColor(entry.Severity)
+}
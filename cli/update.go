@@ -28,6 +28,15 @@ const (
 	StagingManifestURL    = StagingBaseURL + "packing_slip.json"
 )
 
+// channelBaseURL returns the artifacts directory of a release channel,
+// production unless staging is asked for.
+func channelBaseURL(channel string) string {
+	if channel == "staging" {
+		return StagingBaseURL
+	}
+	return ProductionBaseURL
+}
+
 // userAgent returns the user agent string the client should use.
 func userAgent() string {
 	hostname, _ := os.Hostname()
@@ -163,23 +172,37 @@ func replaceCurrentVersion(binary []byte) error {
 	return nil
 }
 
-func Update(staging bool, dryRun bool) (err error) {
+// UpdateOptions controls which release Update installs and how.
+type UpdateOptions struct {
+	// Channel is the release channel to update from: production or staging.
+	Channel       string
+	Version       string
+	DryRun        bool
+	SkipSignature bool
+}
+
+func Update(opts UpdateOptions) (err error) {
 	var manifestBytes []byte
 	var manifestURL string
 	var baseURL string
 
 	// Download the manifest file from the artifacts server.
-	if staging {
-		manifestURL = StagingManifestURL
-		baseURL = StagingBaseURL
+	switch {
+	case opts.Version != "":
+		release, err := findRelease(opts.Version)
+		if err != nil {
+			return err
+		}
+		baseURL = release.BaseURL()
+		manifestURL = baseURL + "packing_slip.json"
 		fmt.Fprintf(
-			os.Stdout, "Fetching staging manifest from %s ...\n", manifestURL,
+			os.Stdout, "Fetching manifest for release %s from %s ...\n", release.Version, manifestURL,
 		)
-	} else {
-		manifestURL = ProductionManifestURL
-		baseURL = ProductionBaseURL
+	default:
+		baseURL = channelBaseURL(opts.Channel)
+		manifestURL = baseURL + "packing_slip.json"
 		fmt.Fprintf(
-			os.Stdout, "Fetching production manifest from %s ...\n", manifestURL,
+			os.Stdout, "Fetching %s manifest from %s ...\n", opts.Channel, manifestURL,
 		)
 	}
 
@@ -187,6 +210,28 @@ func Update(staging bool, dryRun bool) (err error) {
 	if err != nil {
 		return err
 	}
+
+	// Verify the manifest was signed by a trusted release key before we
+	// trust any of the checksums it contains.
+	if opts.SkipSignature {
+		fmt.Fprintf(
+			os.Stderr,
+			"WARNING: skipping manifest signature verification!\n"+
+				"WARNING: the downloaded binary cannot be trusted.\n",
+		)
+	} else {
+		signatureURL := manifestURL + SignatureSuffix
+		fmt.Fprintf(os.Stdout, "Fetching manifest signature from %s ...\n", signatureURL)
+		signatureBytes, err := httpGET(signatureURL)
+		if err != nil {
+			return fmt.Errorf("could not fetch manifest signature: %w", err)
+		}
+		err = verifyManifest(manifestBytes, signatureBytes)
+		if err != nil {
+			return fmt.Errorf("refusing to update: %w", err)
+		}
+		fmt.Fprintln(os.Stdout, "Manifest signature successfully verified.")
+	}
 	manifestBytes = bytes.TrimSpace(manifestBytes)
 
 	// Convert the (hopefully JSON) response into a Manifest struct.
@@ -246,7 +291,7 @@ func Update(staging bool, dryRun bool) (err error) {
 			fmt.Fprintln(os.Stdout, "New binary successfully verified.")
 		}
 
-		if !dryRun {
+		if !opts.DryRun {
 			err = replaceCurrentVersion(binary)
 			if err != nil {
 				return err
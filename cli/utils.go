@@ -8,22 +8,37 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
 type KeaSubnet struct {
-	Total int `json:"total"`
-	Items []struct {
-		SubnetID int `json:"id"`
-	} `json:"items"`
+	Total int             `json:"total"`
+	Items []KeaSubnetItem `json:"items"`
+}
+
+type KeaSubnetItem struct {
+	SubnetID        int      `json:"id"`
+	Subnet          string   `json:"subnet"`
+	AddrUtilization float64  `json:"addrUtilization"`
+	Pools           KeaPools `json:"pools"`
+	LocalSubnets    []struct {
+		AppID    int      `json:"appId"`
+		AppName  string   `json:"appName"`
+		SubnetID int      `json:"id"`
+		Pools    KeaPools `json:"pools"`
+	} `json:"localSubnets"`
 }
 
 type KeaApp struct {
-	Total int `json:"total"`
-	Items []struct {
-		AppName string `json:"name"`
-		AppID   int    `json:"id"`
-	} `json:"items"`
+	Total int          `json:"total"`
+	Items []KeaAppItem `json:"items"`
+}
+
+type KeaAppItem struct {
+	AppName string `json:"name"`
+	AppID   int    `json:"id"`
 }
 
 type KeaLogs struct {
@@ -38,77 +53,66 @@ type KeaLogs struct {
 	} `json:"details"`
 }
 
-func getSubnetID(subnet string, envURL *url.URL, jar *cookiejar.Jar) (subnetid int, err error) {
-	s := KeaSubnet{}
-	client := &http.Client{Jar: jar}
-
-	envURL.Path = "/api/subnets"
-	queryValues := envURL.Query()
-	queryValues.Set("text", subnet)
-	envURL.RawQuery = queryValues.Encode()
+// storkGet fetches path from Stork and decodes the JSON response into out.
+func storkGet(path string, query url.Values, envURL *url.URL, jar *cookiejar.Jar, out interface{}) error {
+	reqURL := *envURL
+	reqURL.Path = path
+	reqURL.RawQuery = query.Encode()
 
-	resp, err := client.Get(envURL.String())
+	resp, err := storkClient(jar).Get(reqURL.String())
 	if err != nil {
-		return 0, errors.New("error looking up subnet ID")
+		return err
 	}
+	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", path, errNotFound)
+	}
 	if resp.StatusCode != 200 {
-		return 0, errors.New("error looking up subnet ID")
+		return fmt.Errorf("stork returned %d for %s", resp.StatusCode, path)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return 0, errors.New("error looking up subnet ID")
+		return err
 	}
-	defer resp.Body.Close()
 
 	if len(body) == 0 {
-		return 0, errors.New("error looking up subnet ID")
-	}
-
-	jsonErr := json.Unmarshal(body, &s)
-	if jsonErr != nil {
-		return 0, errors.New("error looking up subnet ID")
-	}
-
-	if s.Total == 1 {
-		return s.Items[0].SubnetID, nil
-	} else {
-		return 0, errors.New("no results found for subnet")
+		return fmt.Errorf("unexpected empty response for %s", path)
 	}
+	return json.Unmarshal(body, out)
 }
 
-func getAppID(appname string, envURL *url.URL, jar *cookiejar.Jar) (appid int, err error) {
-	a := KeaApp{}
-	client := &http.Client{Jar: jar}
-
-	envURL.Path = "/api/apps"
-	queryValues := envURL.Query()
-	queryValues.Set("limit", "25")
-	envURL.RawQuery = queryValues.Encode()
-
-	resp, err := client.Get(envURL.String())
+// getSubnets returns every subnet in an environment, from the metadata cache
+// when it is fresh.
+func getSubnets(envURL *url.URL, jar *cookiejar.Jar) (*KeaSubnet, error) {
+	items, err := cachedListing(subnetsCacheName(environmentName(envURL)), func() ([]KeaSubnetItem, error) {
+		return getAllPages[KeaSubnetItem]("/api/subnets", url.Values{}, envURL, jar)
+	})
 	if err != nil {
-		return 0, errors.New("error looking up app ID")
-	}
-
-	if resp.StatusCode != 200 {
-		return 0, errors.New("error looking up app ID")
+		return nil, fmt.Errorf("error looking up subnets: %w", err)
 	}
+	return &KeaSubnet{Total: len(items), Items: items}, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// getApps returns every Kea app in an environment, from the metadata cache
+// when it is fresh.
+func getApps(envURL *url.URL, jar *cookiejar.Jar) (*KeaApp, error) {
+	query := url.Values{}
+	query.Set("app", "kea")
+	items, err := cachedListing(appsCacheName(environmentName(envURL)), func() ([]KeaAppItem, error) {
+		return getAllPages[KeaAppItem]("/api/apps", query, envURL, jar)
+	})
 	if err != nil {
-		return 0, errors.New("error looking up app ID")
-	}
-	defer resp.Body.Close()
-
-	if len(body) == 0 {
-		return 0, errors.New("error looking up app ID")
+		return nil, fmt.Errorf("error looking up apps: %w", err)
 	}
+	return &KeaApp{Total: len(items), Items: items}, nil
+}
 
-	jsonErr := json.Unmarshal(body, &a)
-	if jsonErr != nil {
-		return 0, errors.New("error looking up app ID")
+func getAppID(appname string, envURL *url.URL, jar *cookiejar.Jar) (appid int, err error) {
+	a, err := getApps(envURL, jar)
+	if err != nil {
+		return 0, fmt.Errorf("error looking up app ID: %w", err)
 	}
 
 	if a.Total >= 1 {
@@ -123,46 +127,54 @@ func getAppID(appname string, envURL *url.URL, jar *cookiejar.Jar) (appid int, e
 
 func getLogID(logname string, appname string, envURL *url.URL, jar *cookiejar.Jar) (logid int, err error) {
 	l := KeaLogs{}
-	client := &http.Client{Jar: jar}
+	client := storkClient(jar)
 
 	// Since everything is ID based, we need to get that value first
 	appId, err := getAppID(appname, envURL, jar)
 	if err != nil {
-		return 0, errors.New("error looking up log id")
+		return 0, fmt.Errorf("error looking up log id: %w", err)
 	}
 
 	envURL.Path = fmt.Sprintf("/api/apps/%d", appId)
+	envURL.RawQuery = ""
 
 	resp, err := client.Get(envURL.String())
 	if err != nil {
-		return 0, errors.New("error looking up app ID")
+		return 0, fmt.Errorf("error looking up app details: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// A 404 means the cached app ID is stale; look it up again from Stork.
+	if resp.StatusCode == http.StatusNotFound && !refreshMetadata {
+		invalidateMetadata(environmentName(envURL))
+		refreshMetadata = true
+		return getLogID(logname, appname, envURL, jar)
 	}
 
 	if resp.StatusCode != 200 {
-		return 0, errors.New("error looking up app details")
+		return 0, fmt.Errorf("error looking up app details: stork returned %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return 0, errors.New("error parsing app details")
+		return 0, fmt.Errorf("error reading app details: %w", err)
 	}
-	defer resp.Body.Close()
 
 	if len(body) == 0 {
-		return 0, errors.New("unexpected empty response")
+		return 0, errors.New("error looking up app details: unexpected empty response")
 	}
 
 	jsonErr := json.Unmarshal(body, &l)
 	if jsonErr != nil {
-		return 0, errors.New("error parsing response")
+		return 0, fmt.Errorf("error parsing app details: %w", jsonErr)
 	}
 
 	for _, daemon := range l.Details.Daemons {
-		if daemon.LogTargets[0].LogName == logname {
+		if len(daemon.LogTargets) > 0 && daemon.LogTargets[0].LogName == logname {
 			return daemon.LogTargets[0].LogId, nil
 		}
 	}
-	return 0, errors.New("no results found for log")
+	return 0, fmt.Errorf("no results found for log %s on %s", logname, appname)
 }
 
 func isStage2(region string) bool {
@@ -171,3 +183,14 @@ func isStage2(region string) bool {
 	}
 	return false
 }
+
+// cacheDir returns the directory dhcli keeps its on-disk state in, creating
+// it if needed.
+func cacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, BinaryName)
+	return dir, os.MkdirAll(dir, 0o700)
+}
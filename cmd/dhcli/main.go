@@ -6,22 +6,60 @@ import (
 	"github.com/alecthomas/kong"
 	"github.com/sseekamp/dhcli/cli"
 	"runtime"
+	"strings"
+	"time"
 )
 
 type versionCmd struct{}
 
 type updateCmd struct {
-	Staging bool `kong:"optional,short='s',help='Update to the latest staging build (default is the latest production build).'"`
-	DryRun  bool `kong:"optional,short='d',help='Perform a dry run (do not actually update the binary).'"`
+	Staging bool   `kong:"optional,short='s',help='Update to the latest staging build (same as --update-channel staging).'"`
+	DryRun  bool   `kong:"optional,short='d',help='Perform a dry run (do not actually update the binary).'"`
+	Version string `kong:"optional,name='version',help='Install a specific published release by commit or tag.'"`
+	List    bool   `kong:"optional,short='l',help='List published releases for each channel.'"`
+
+	InsecureSkipSignature bool `kong:"optional,name='insecure-skip-signature',help='Do not verify the release manifest signature (unsafe).'"`
 }
 
 var dhcli struct {
-	Search  cli.SearchCmd `kong:"cmd='',help='Search for an active lease'"`
-	Status  cli.StatusCmd `kong:"cmd='',help='Show Kea daemon status'"`
-	Logs    cli.LogsCmd   `kong:"cmd='',help='Show logs from Kea instance'"`
-	Res     cli.ResCmd    `kong:"cmd='',help='Show address reservations'"`
-	Update  updateCmd     `kong:"cmd='',help='Update dhcli version'"`
-	Version versionCmd    `kong:"cmd='',help='Show dhcli version'"`
+	Verbose bool `kong:"optional,short='v',help='Log Stork requests and responses to stderr.'"`
+	Debug   bool `kong:"optional,help='Like --verbose, but also log headers and bodies (secrets are redacted).'"`
+
+	NoUpdateCheck       bool          `kong:"optional,name='no-update-check',env='DHCLI_NO_UPDATE_CHECK',help='Do not check for newer dhcli releases.'"`
+	UpdateCheckInterval time.Duration `kong:"optional,name='update-check-interval',env='DHCLI_UPDATE_CHECK_INTERVAL',default='24h',help='How often to check for newer dhcli releases.'"`
+	UpdateChannel       string        `kong:"optional,name='update-channel',env='DHCLI_UPDATE_CHANNEL',default='production',enum='production,staging',help='Release channel to check and update from: production or staging.'"`
+	Refresh             bool          `kong:"optional,help='Ignore the cached Kea app and subnet listings.'"`
+	CacheTTL            time.Duration `kong:"optional,name='cache-ttl',env='DHCLI_CACHE_TTL',default='1h',help='How long cached Kea app and subnet listings are used.'"`
+	UTC                 bool          `kong:"optional,name='utc',env='DHCLI_UTC',help='Show times in UTC instead of local time.'"`
+
+	Search    cli.SearchCmd    `kong:"cmd='',help='Search for an active lease'"`
+	Status    cli.StatusCmd    `kong:"cmd='',help='Show Kea daemon status'"`
+	Logs      cli.LogsCmd      `kong:"cmd='',help='Show logs from Kea instance'"`
+	Res       cli.ResCmd       `kong:"cmd='',help='Show address reservations'"`
+	Apps      cli.AppsCmd      `kong:"cmd='',help='List Kea apps and their daemons'"`
+	Machines  cli.MachinesCmd  `kong:"cmd='',help='Show machines and Stork agent health'"`
+	Config    cli.ConfigCmd    `kong:"cmd='',help='Inspect Kea daemon configuration'"`
+	Stats     cli.StatsCmd     `kong:"cmd='',help='Show Kea statistics of an instance'"`
+	Lease     cli.LeaseCmd     `kong:"cmd='',help='Change a single lease'"`
+	Leases    cli.LeasesCmd    `kong:"cmd='',help='Work with leases in bulk'"`
+	Conflicts cli.ConflictsCmd `kong:"cmd='',help='Find conflicts between leases and reservations'"`
+	Free      cli.FreeCmd      `kong:"cmd='',help='Find unused addresses in a subnet'"`
+	Oui       cli.OuiCmd       `kong:"cmd='',help='Look up the vendor of a MAC address'"`
+	Update    updateCmd        `kong:"cmd='',help='Update dhcli version'"`
+	Version   versionCmd       `kong:"cmd='',help='Show dhcli version'"`
+
+	Tui        cli.TuiCmd        `kong:"cmd='',help='Interactive dashboard of daemons, subnets, events and logs'"`
+	Completion cli.CompletionCmd `kong:"cmd='',help='Generate shell completion script'"`
+	Complete   cli.CompleteCmd   `kong:"cmd='',name='__complete',hidden='',help='Print dynamic completion candidates'"`
+}
+
+// noUpdateCheck lists the commands that never print the update notice.
+var noUpdateCheck = map[string]bool{
+	"update":     true,
+	"version":    true,
+	"completion": true,
+	"tui":        true,
+	"__complete": true,
 }
 
 func (d *versionCmd) Run() error {
@@ -31,11 +69,40 @@ func (d *versionCmd) Run() error {
 }
 
 func (d *updateCmd) Run() error {
-	return cli.Update(d.Staging, d.DryRun)
+	if d.List {
+		return cli.ListReleases()
+	}
+	channel := dhcli.UpdateChannel
+	if d.Staging {
+		channel = "staging"
+	}
+	return cli.Update(cli.UpdateOptions{
+		Channel:       channel,
+		Version:       d.Version,
+		DryRun:        d.DryRun,
+		SkipSignature: d.InsecureSkipSignature,
+	})
 }
 
 func main() {
 	ctx := kong.Parse(&dhcli)
+
+	switch {
+	case dhcli.Debug:
+		cli.SetTraceLevel(cli.TraceBodies)
+	case dhcli.Verbose:
+		cli.SetTraceLevel(cli.TraceRequests)
+	}
+
+	cli.SetCacheOptions(dhcli.CacheTTL, dhcli.Refresh)
+	cli.SetUTC(dhcli.UTC)
+
+	var check *cli.UpdateCheck
+	if !dhcli.NoUpdateCheck && !noUpdateCheck[strings.Fields(ctx.Command())[0]] {
+		check = cli.CheckForUpdate(dhcli.UpdateCheckInterval, dhcli.UpdateChannel)
+	}
+
 	err := ctx.Run()
+	cli.PrintUpdateNotice(check)
 	ctx.FatalIfErrorf(err)
 }
@@ -9,6 +9,19 @@ if [ ! -x "$(which packingslip)" ]; then
     exit 1
 fi
 
+if [ ! -x "$(which minisign)" ]; then
+    echo "minisign binary not found" 1>&2
+    exit 1
+fi
+
+if [ "${MINISIGN_SECRET_KEY:-}" == "" ]; then
+    echo "missing environment variable: MINISIGN_SECRET_KEY" 1>&2
+    exit 1
+fi
+
+# A release without a trusted key embedded could never update itself.
+make check-release-keys
+
 do_build() {
     export GOOS="$1"
     export GOARCH="$2"
@@ -39,3 +52,18 @@ find . -type f | sed 's/^\.\///' | grep -v 'packing_slip\.json' | \
     --version "$(git rev-parse HEAD)" \
     > packing_slip.json
 
+echo "Signing artifacts/packing_slip.json..."
+minisign -S -l -s "$MINISIGN_SECRET_KEY" -m packing_slip.json \
+    -t "dhcli $(git rev-parse HEAD)"
+
+# Refuse to publish a release the embedded keys can't verify.
+verified=false
+while read -r key; do
+    if minisign -V -P "$key" -m packing_slip.json > /dev/null 2>&1; then
+        verified=true
+    fi
+done < <(grep -Ev '^[[:space:]]*(#|untrusted comment:|$)' ../cli/release_keys.pub)
+if [ "$verified" != true ]; then
+    echo "packing_slip.json.minisig does not verify against cli/release_keys.pub" 1>&2
+    exit 1
+fi
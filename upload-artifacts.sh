@@ -15,7 +15,19 @@ if [ "$ARTIFACT_VERSION" == "" ]; then
     exit 1
 fi
 
-BASE_URL="https://artifacts/artifactory/artifacts-dev-local/$PRODUCT/$ARTIFACT_VERSION"
+ROOT_URL="https://artifacts/artifactory/artifacts-dev-local/$PRODUCT"
+BASE_URL="$ROOT_URL/$ARTIFACT_VERSION"
+INDEX_URL="$ROOT_URL/index.json"
+
+# The channel (production or staging) and optional tag recorded in the
+# release index used by `dhcli update --list` and `--version`.
+ARTIFACT_CHANNEL="${ARTIFACT_CHANNEL:-staging}"
+ARTIFACT_TAG="${ARTIFACT_TAG:-}"
+
+if [ ! -x "$(which jq)" ]; then
+    echo "jq binary not found" 1>&2
+    exit 1
+fi
 
 if [ "$ARTIFACTORY_USERNAME" == "" ]; then
     echo "missing environment variable: ARTIFACTORY_USERNAME" 1>&2
@@ -40,3 +52,28 @@ for file in $FILES; do
         -T "$file" \
         "${BASE_URL}/${file}"
 done
+
+echo "Updating release index at $INDEX_URL..."
+SIZES="{}"
+for file in $FILES; do
+    if [[ "$file" == packing_slip.json* ]]; then
+        continue
+    fi
+    SIZES="$(jq -c --arg path "$file" --argjson size "$(wc -c < "$file")" \
+        '. + {($path): $size}' <<< "$SIZES")"
+done
+
+INDEX="$(curl -sf --user "$ARTIFACTORY_USERNAME:$ARTIFACTORY_PASSWORD" "$INDEX_URL" || echo '{"releases": []}')"
+jq --arg version "$ARTIFACT_VERSION" \
+    --arg tag "$ARTIFACT_TAG" \
+    --arg channel "$ARTIFACT_CHANNEL" \
+    --arg date "$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
+    --argjson sizes "$SIZES" \
+    '.releases = ([.releases[] | select(.version != $version)] +
+        [{version: $version, tag: $tag, channel: $channel, date: $date, sizes: $sizes}])' \
+    <<< "$INDEX" > ../index.json
+
+curl --user "$ARTIFACTORY_USERNAME:$ARTIFACTORY_PASSWORD" \
+    -T ../index.json \
+    "$INDEX_URL"
+rm -f ../index.json